@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+)
+
+// checkBaseline ensures that the outcomes that are covered
+// in the baseline from the -baseline option are still covered.
+// Other than a global coverage threshold, this prevents the coverage
+// from silently eroding, while still allowing to remove code.
+//
+// With -update-baseline, the baseline is created if it doesn't exist yet,
+// and it is rewritten if the coverage improved,
+// that is, if an outcome is newly covered
+// or if the ratio of the covered outcomes increased.
+// Removing covered conditions doesn't update the baseline.
+func (g *gobco) checkBaseline(conds []condition) {
+	baseline, err := g.load(g.baseline)
+	if err != nil && os.IsNotExist(err) && g.updateBaseline {
+		g.writeBaseline(conds)
+		return
+	}
+	if err != nil {
+		g.errf("%s", err)
+		g.exitCode = 1
+		return
+	}
+
+	pairs, _, _ := matchConds(baseline, conds)
+	lost, gained := compareOutcomes(pairs)
+	if len(lost) > 0 {
+		for _, line := range lost {
+			g.errf("%s", line)
+		}
+		g.errf("%d outcomes that are covered in the baseline %s "+
+			"are no longer covered", len(lost), g.baseline)
+		g.exitCode = 1
+		return
+	}
+
+	if g.updateBaseline && (len(gained) > 0 || improved(baseline, conds)) {
+		g.writeBaseline(conds)
+	}
+}
+
+// improved returns whether the ratio of the covered outcomes
+// is higher in the current conditions than in the baseline.
+func improved(baseline, conds []condition) bool {
+	oldCovered, oldTotal := countOutcomes(baseline)
+	newCovered, newTotal := countOutcomes(conds)
+	return newCovered*oldTotal > oldCovered*newTotal
+}
+
+// writeBaseline writes the conditions to the baseline file,
+// in the same format as the stats file.
+// To keep the file stable between runs,
+// the counts are reduced to 0 or 1, and the tests are omitted.
+func (g *gobco) writeBaseline(conds []condition) {
+	capped := func(n int) int {
+		if n > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	var baseline []condition
+	for _, cond := range conds {
+		baseline = append(baseline, condition{
+			Start:      cond.Start,
+			Code:       cond.Code,
+			TrueCount:  capped(cond.TrueCount),
+			FalseCount: capped(cond.FalseCount),
+			Kind:       cond.Kind,
+			Class:      cond.Class,
+			End:        cond.End,
+			Func:       cond.Func,
+			Type:       cond.Type,
+		})
+	}
+
+	if err := g.persist(g.baseline, baseline); err != nil {
+		g.errf("%s", err)
+		g.exitCode = 1
+		return
+	}
+	g.errf("updated the baseline %s", g.baseline)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// The Cobertura XML format, as described in
+// http://cobertura.sourceforge.net/xml/coverage-04.dtd.
+//
+// Cobertura reports lines and branches.
+// Gobco only knows the lines that contain conditions,
+// so the line rate refers to these lines only,
+// and each outcome of a condition counts as a branch.
+
+type coberturaCoverage struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        float64            `xml:"line-rate,attr"`
+	BranchRate      float64            `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      float64            `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Sources         []string           `xml:"sources>source"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   float64          `xml:"line-rate,attr"`
+	BranchRate float64          `xml:"branch-rate,attr"`
+	Complexity float64          `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string          `xml:"name,attr"`
+	Filename   string          `xml:"filename,attr"`
+	LineRate   float64         `xml:"line-rate,attr"`
+	BranchRate float64         `xml:"branch-rate,attr"`
+	Complexity float64         `xml:"complexity,attr"`
+	Methods    struct{}        `xml:"methods"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number            int    `xml:"number,attr"`
+	Hits              int    `xml:"hits,attr"`
+	Branch            bool   `xml:"branch,attr"`
+	ConditionCoverage string `xml:"condition-coverage,attr"`
+}
+
+// coberturaCounts accumulates the lines and branches
+// of a class, a package or the whole report.
+type coberturaCounts struct {
+	linesCovered, linesValid       int
+	branchesCovered, branchesValid int
+}
+
+func (c *coberturaCounts) add(other coberturaCounts) {
+	c.linesCovered += other.linesCovered
+	c.linesValid += other.linesValid
+	c.branchesCovered += other.branchesCovered
+	c.branchesValid += other.branchesValid
+}
+
+func (c coberturaCounts) lineRate() float64 {
+	return rate(c.linesCovered, c.linesValid)
+}
+
+func (c coberturaCounts) branchRate() float64 {
+	return rate(c.branchesCovered, c.branchesValid)
+}
+
+// writeCobertura writes the conditions in the Cobertura XML format.
+// Each file becomes a class, and each directory becomes a package.
+func writeCobertura(w io.Writer, conds []condition, now time.Time) error {
+	cov := coberturaCoverage{
+		Version:   "gobco " + version,
+		Timestamp: now.UnixNano() / int64(time.Millisecond),
+		Sources:   []string{"."},
+	}
+
+	var total coberturaCounts
+	pkgIndex := map[string]int{}
+	var pkgCounts []coberturaCounts
+	for _, file := range condsByFile(conds) {
+		class, counts := coberturaFile(file)
+
+		dir := filepath.ToSlash(filepath.Dir(file.name))
+		i, ok := pkgIndex[dir]
+		if !ok {
+			i = len(cov.Packages)
+			pkgIndex[dir] = i
+			cov.Packages = append(cov.Packages, coberturaPackage{Name: dir})
+			pkgCounts = append(pkgCounts, coberturaCounts{})
+		}
+		pkg := &cov.Packages[i]
+		pkg.Classes = append(pkg.Classes, class)
+		pkgCounts[i].add(counts)
+		pkg.LineRate = pkgCounts[i].lineRate()
+		pkg.BranchRate = pkgCounts[i].branchRate()
+
+		total.add(counts)
+	}
+
+	cov.LineRate = total.lineRate()
+	cov.BranchRate = total.branchRate()
+	cov.LinesCovered = total.linesCovered
+	cov.LinesValid = total.linesValid
+	cov.BranchesCovered = total.branchesCovered
+	cov.BranchesValid = total.branchesValid
+
+	if _, err := io.WriteString(w, xml.Header+
+		"<!DOCTYPE coverage SYSTEM "+
+		"\"http://cobertura.sourceforge.net/xml/coverage-04.dtd\">\n"); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "\t")
+	if err := encoder.Encode(cov); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// coberturaFile converts the conditions from a single file
+// to a class, with a line for each line that contains conditions.
+func coberturaFile(file fileConds) (coberturaClass, coberturaCounts) {
+	type lineCounts struct {
+		hits              int
+		covered, outcomes int
+	}
+
+	lines := map[int]*lineCounts{}
+	for _, cond := range file.conds {
+		pos, _ := parsePosition(cond.Start)
+		line := lines[pos.line]
+		if line == nil {
+			line = &lineCounts{}
+			lines[pos.line] = line
+		}
+		// The hits of a line are approximated by the evaluations
+		// of its most frequently evaluated condition.
+		if hits := cond.TrueCount + cond.FalseCount; hits > line.hits {
+			line.hits = hits
+		}
+		covered, outcomes := cond.outcomes()
+		line.covered += covered
+		line.outcomes += outcomes
+	}
+
+	var numbers []int
+	for number := range lines {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	class := coberturaClass{
+		Name:     filepath.Base(file.name),
+		Filename: filepath.ToSlash(file.name),
+	}
+	var counts coberturaCounts
+	for _, number := range numbers {
+		line := lines[number]
+		class.Lines = append(class.Lines, coberturaLine{
+			number,
+			line.hits,
+			true,
+			fmt.Sprintf("%d%% (%d/%d)",
+				100*line.covered/line.outcomes, line.covered, line.outcomes),
+		})
+		if line.hits > 0 {
+			counts.linesCovered++
+		}
+		counts.linesValid++
+		counts.branchesCovered += line.covered
+		counts.branchesValid += line.outcomes
+	}
+	class.LineRate = counts.lineRate()
+	class.BranchRate = counts.branchRate()
+	return class, counts
+}
+
+// rate returns the ratio of covered to total, or 1 if there is nothing
+// to cover, as is common in coverage reports.
+func rate(covered, total int) float64 {
+	if total == 0 {
+		return 1
+	}
+	return float64(covered) / float64(total)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_writeCobertura(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	conds := []condition{
+		{Start: "pkg/a.go:3:5", Code: "a", TrueCount: 2, FalseCount: 1},
+		{Start: "pkg/a.go:3:10", Code: "b", TrueCount: 2, FalseCount: 0},
+		{Start: "pkg/a.go:7:5", Code: "c", TrueCount: 0, FalseCount: 0},
+		{Start: "pkg/sub/b.go:4:2", Code: "return 0", TrueCount: 1, FalseCount: 0, Kind: "return", Class: "return"},
+	}
+
+	var sb strings.Builder
+	err := writeCobertura(&sb, conds, time.Unix(1700000000, 0))
+
+	s.CheckEquals(err, nil)
+	s.CheckEquals(sb.String(), ""+
+		"<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"+
+		"<!DOCTYPE coverage SYSTEM \"http://cobertura.sourceforge.net/xml/coverage-04.dtd\">\n"+
+		"<coverage line-rate=\"0.6666666666666666\" branch-rate=\"0.5714285714285714\""+
+		" lines-covered=\"2\" lines-valid=\"3\" branches-covered=\"4\" branches-valid=\"7\""+
+		" complexity=\"0\" version=\"gobco "+version+"\" timestamp=\"1700000000000\">\n"+
+		"\t<sources>\n"+
+		"\t\t<source>.</source>\n"+
+		"\t</sources>\n"+
+		"\t<packages>\n"+
+		"\t\t<package name=\"pkg\" line-rate=\"0.5\" branch-rate=\"0.5\" complexity=\"0\">\n"+
+		"\t\t\t<classes>\n"+
+		"\t\t\t\t<class name=\"a.go\" filename=\"pkg/a.go\" line-rate=\"0.5\" branch-rate=\"0.5\" complexity=\"0\">\n"+
+		"\t\t\t\t\t<methods></methods>\n"+
+		"\t\t\t\t\t<lines>\n"+
+		"\t\t\t\t\t\t<line number=\"3\" hits=\"3\" branch=\"true\" condition-coverage=\"75% (3/4)\"></line>\n"+
+		"\t\t\t\t\t\t<line number=\"7\" hits=\"0\" branch=\"true\" condition-coverage=\"0% (0/2)\"></line>\n"+
+		"\t\t\t\t\t</lines>\n"+
+		"\t\t\t\t</class>\n"+
+		"\t\t\t</classes>\n"+
+		"\t\t</package>\n"+
+		"\t\t<package name=\"pkg/sub\" line-rate=\"1\" branch-rate=\"1\" complexity=\"0\">\n"+
+		"\t\t\t<classes>\n"+
+		"\t\t\t\t<class name=\"b.go\" filename=\"pkg/sub/b.go\" line-rate=\"1\" branch-rate=\"1\" complexity=\"0\">\n"+
+		"\t\t\t\t\t<methods></methods>\n"+
+		"\t\t\t\t\t<lines>\n"+
+		"\t\t\t\t\t\t<line number=\"4\" hits=\"1\" branch=\"true\" condition-coverage=\"100% (1/1)\"></line>\n"+
+		"\t\t\t\t\t</lines>\n"+
+		"\t\t\t\t</class>\n"+
+		"\t\t\t</classes>\n"+
+		"\t\t</package>\n"+
+		"\t</packages>\n"+
+		"</coverage>\n")
+}
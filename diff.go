@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// diff compares the coverage from an old and a new stats file,
+// listing the outcomes that are no longer covered or newly covered,
+// as well as the conditions that were added or removed.
+// If any outcome is no longer covered, the exit status is 1.
+func (g *gobco) diff() {
+	if len(g.reportArgs) != 2 {
+		g.errf("gobco diff: expected exactly two stats files, got %d",
+			len(g.reportArgs))
+		g.exitCode = 2
+		return
+	}
+
+	oldConds, err := g.loadStats(g.reportArgs[:1])
+	if err != nil {
+		g.errf("%s", err)
+		g.exitCode = 1
+		return
+	}
+	newConds, err := g.loadStats(g.reportArgs[1:])
+	if err != nil {
+		g.errf("%s", err)
+		g.exitCode = 1
+		return
+	}
+
+	g.printDiff(oldConds, newConds)
+}
+
+func (g *gobco) printDiff(oldConds, newConds []condition) {
+	pairs, added, removed := matchConds(oldConds, newConds)
+
+	kind := "Condition coverage"
+	if g.branch {
+		kind = "Branch coverage"
+	}
+	oldCovered, oldTotal := countOutcomes(oldConds)
+	newCovered, newTotal := countOutcomes(newConds)
+	g.outf("%s: %d/%d (%s) -> %d/%d (%s)", kind,
+		oldCovered, oldTotal, percentage(oldCovered, oldTotal),
+		newCovered, newTotal, percentage(newCovered, newTotal))
+
+	lost, gained := compareOutcomes(pairs)
+	g.printSection("No longer covered", lost)
+	g.printSection("Newly covered", gained)
+	var addedLines, removedLines []string
+	for _, cond := range added {
+		addedLines = append(addedLines, fmt.Sprintf("%s: %s", cond.Start, cond.noun()))
+	}
+	for _, cond := range removed {
+		removedLines = append(removedLines, fmt.Sprintf("%s: %s", cond.Start, cond.noun()))
+	}
+	g.printSection("Added", addedLines)
+	g.printSection("Removed", removedLines)
+
+	if len(lost) > 0 {
+		g.errf("%d outcomes are no longer covered", len(lost))
+		g.exitCode = 1
+	}
+}
+
+// printSection prints the heading and the indented lines,
+// or nothing if there are no lines.
+func (g *gobco) printSection(heading string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	g.outf("%s:", heading)
+	for _, line := range lines {
+		g.outf("  %s", line)
+	}
+}
+
+// compareOutcomes describes the outcomes that were covered in the old
+// condition but not in the new one, and vice versa.
+func compareOutcomes(pairs []condPair) (lost, gained []string) {
+	for _, pair := range pairs {
+		for _, value := range []bool{true, false} {
+			wasCovered, known := pair.old.covers(value)
+			isCovered, _ := pair.new.covers(value)
+			switch {
+			case !known:
+			case wasCovered && !isCovered:
+				lost = append(lost, fmt.Sprintf("%s: %s is no longer %s",
+					pair.new.Start, pair.new.noun(), pair.new.outcomeName(value)))
+			case !wasCovered && isCovered:
+				gained = append(gained, fmt.Sprintf("%s: %s is now %s",
+					pair.new.Start, pair.new.noun(), pair.new.outcomeName(value)))
+			}
+		}
+	}
+	return
+}
+
+// condPair is a condition from the old stats file
+// together with the corresponding condition from the new stats file.
+type condPair struct {
+	old condition
+	new condition
+}
+
+// matchConds pairs the conditions from two runs of the same code,
+// which may have been edited in between.
+//
+// Conditions are matched in several passes, from strict to loose.
+// The first pass matches the exact location and code.
+// The second pass tolerates moved lines,
+// matching the code within the same function.
+// The third pass also tolerates renamed functions,
+// matching the code within the same file.
+// In each pass, conditions with the same key are paired
+// by the smallest distance between their lines,
+// so that inserting a condition before similar ones
+// doesn't shift all pairs.
+func matchConds(oldConds, newConds []condition) (pairs []condPair, added, removed []condition) {
+	file := func(cond condition) string {
+		pos, _ := parsePosition(cond.Start)
+		return pos.filename
+	}
+	keys := []func(condition) string{
+		func(cond condition) string {
+			return cond.Start + "\x00" + cond.Code
+		},
+		func(cond condition) string {
+			return file(cond) + "\x00" + cond.Func + "\x00" + cond.Kind + "\x00" + cond.Code
+		},
+		func(cond condition) string {
+			return file(cond) + "\x00" + cond.Kind + "\x00" + cond.Code
+		},
+	}
+	distance := func(a, b condition) int {
+		posA, _ := parsePosition(a.Start)
+		posB, _ := parsePosition(b.Start)
+		if posA.line < posB.line {
+			return posB.line - posA.line
+		}
+		return posA.line - posB.line
+	}
+
+	oldMatched := make([]bool, len(oldConds))
+	newMatch := make([]int, len(newConds))
+	for j := range newMatch {
+		newMatch[j] = -1
+	}
+
+	for _, key := range keys {
+		candidates := map[string][]int{}
+		for i, cond := range oldConds {
+			if !oldMatched[i] {
+				k := key(cond)
+				candidates[k] = append(candidates[k], i)
+			}
+		}
+
+		type match struct{ i, j, distance int }
+		var matches []match
+		for j, cond := range newConds {
+			if newMatch[j] >= 0 {
+				continue
+			}
+			for _, i := range candidates[key(cond)] {
+				matches = append(matches, match{i, j, distance(oldConds[i], cond)})
+			}
+		}
+
+		// For equal distances, the conditions are paired in their order.
+		sort.SliceStable(matches, func(a, b int) bool {
+			return matches[a].distance < matches[b].distance
+		})
+		for _, m := range matches {
+			if !oldMatched[m.i] && newMatch[m.j] < 0 {
+				oldMatched[m.i] = true
+				newMatch[m.j] = m.i
+			}
+		}
+	}
+
+	for j, cond := range newConds {
+		if i := newMatch[j]; i >= 0 {
+			pairs = append(pairs, condPair{oldConds[i], cond})
+		} else {
+			added = append(added, cond)
+		}
+	}
+	for i, cond := range oldConds {
+		if !oldMatched[i] {
+			removed = append(removed, cond)
+		}
+	}
+	return
+}
+
+// countOutcomes returns the number of covered and possible outcomes.
+func countOutcomes(conds []condition) (covered, total int) {
+	for _, cond := range conds {
+		c, t := cond.outcomes()
+		covered += c
+		total += t
+	}
+	return
+}
+
+// covers returns whether the outcome of the condition is covered,
+// and whether the condition has this outcome at all.
+// Enum values, return statements and calls to panic
+// only have the outcome true.
+func (c condition) covers(value bool) (covered, ok bool) {
+	if value {
+		return c.TrueCount > 0, true
+	}
+	return c.FalseCount > 0, c.Kind == ""
+}
+
+// noun describes the condition, such as 'condition "i > 0"'
+// or 'return "return 0"'.
+func (c condition) noun() string {
+	kind := c.Kind
+	if kind == "" {
+		kind = "condition"
+	}
+	return fmt.Sprintf("%s %q", kind, c.Code)
+}
+
+// outcomeName returns "true" or "false" for a condition,
+// and "reached" for the single outcome of the other kinds.
+func (c condition) outcomeName(value bool) string {
+	if c.Kind != "" {
+		return "reached"
+	}
+	return fmt.Sprintf("%v", value)
+}
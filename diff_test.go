@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_matchConds(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	oldConds := []condition{
+		{Start: "a.go:3:5", Code: "a", TrueCount: 1, FalseCount: 1, Func: "F"},
+		{Start: "a.go:5:5", Code: "a", TrueCount: 1, FalseCount: 0, Func: "F"},
+		{Start: "a.go:9:5", Code: "b", TrueCount: 1, FalseCount: 0, Func: "G"},
+		{Start: "a.go:12:5", Code: "c", TrueCount: 1, FalseCount: 0, Func: "G"},
+	}
+	newConds := []condition{
+		{Start: "a.go:3:5", Code: "x", TrueCount: 0, FalseCount: 1, Func: "F"},
+		{Start: "a.go:4:5", Code: "a", TrueCount: 1, FalseCount: 1, Func: "F"},
+		{Start: "a.go:6:5", Code: "a", TrueCount: 1, FalseCount: 1, Func: "F"},
+		{Start: "a.go:10:5", Code: "b", TrueCount: 0, FalseCount: 1, Func: "H"},
+	}
+
+	pairs, added, removed := matchConds(oldConds, newConds)
+
+	s.CheckEquals(pairs, []condPair{
+		{oldConds[0], newConds[1]},
+		{oldConds[1], newConds[2]},
+		{oldConds[2], newConds[3]},
+	})
+	s.CheckEquals(added, []condition{newConds[0]})
+	s.CheckEquals(removed, []condition{oldConds[3]})
+}
+
+// A condition that is inserted before similar conditions
+// doesn't shift the pairs of the existing conditions.
+func Test_matchConds__insertion_before_duplicates(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	oldConds := []condition{
+		{Start: "a.go:10:5", Code: "err != nil", TrueCount: 1, FalseCount: 0, Class: "error", Func: "F"},
+		{Start: "a.go:20:5", Code: "err != nil", TrueCount: 0, FalseCount: 1, Class: "error", Func: "F"},
+	}
+	newConds := []condition{
+		{Start: "a.go:5:5", Code: "err != nil", TrueCount: 0, FalseCount: 0, Class: "error", Func: "F"},
+		{Start: "a.go:11:5", Code: "err != nil", TrueCount: 1, FalseCount: 0, Class: "error", Func: "F"},
+		{Start: "a.go:21:5", Code: "err != nil", TrueCount: 0, FalseCount: 1, Class: "error", Func: "F"},
+	}
+
+	pairs, added, removed := matchConds(oldConds, newConds)
+
+	s.CheckEquals(pairs, []condPair{
+		{oldConds[0], newConds[1]},
+		{oldConds[1], newConds[2]},
+	})
+	s.CheckEquals(added, []condition{newConds[0]})
+	s.CheckEquals(removed, []condition(nil))
+}
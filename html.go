@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// writeHTML writes an HTML report that shows the source code of each
+// file that contains conditions, with the conditions highlighted
+// according to their coverage, similar to 'go tool cover -html'.
+func writeHTML(filename string, conds []condition) error {
+	var sb strings.Builder
+
+	sb.WriteString(htmlHeader)
+
+	files := condsByFile(conds)
+	covered, total := 0, 0
+	for _, file := range files {
+		cov, outcomes := file.outcomes()
+		covered += cov
+		total += outcomes
+	}
+
+	sb.WriteString("<table class=\"index\">\n")
+	sb.WriteString("<tr><th>File</th><th>Covered</th><th>Coverage</th></tr>\n")
+	for i, file := range files {
+		cov, outcomes := file.outcomes()
+		sb.WriteString(fmt.Sprintf(
+			"<tr><td><a href=\"#file%d\">%s</a></td><td>%d/%d</td><td>%s</td></tr>\n",
+			i, html.EscapeString(filepath.ToSlash(file.name)),
+			cov, outcomes, percentage(cov, outcomes)))
+	}
+	sb.WriteString(fmt.Sprintf(
+		"<tr><th>Total</th><th>%d/%d</th><th>%s</th></tr>\n",
+		covered, total, percentage(covered, total)))
+	sb.WriteString("</table>\n")
+
+	for i, file := range files {
+		sb.WriteString(fmt.Sprintf("<h2 id=\"file%d\">%s</h2>\n",
+			i, html.EscapeString(filepath.ToSlash(file.name))))
+
+		src, err := os.ReadFile(file.name)
+		if err != nil {
+			sb.WriteString(fmt.Sprintf("<p>The source is not available: %s</p>\n",
+				html.EscapeString(err.Error())))
+			continue
+		}
+		sb.WriteString("<pre>")
+		sb.WriteString(annotateHTML(string(src), file.conds))
+		sb.WriteString("</pre>\n")
+	}
+
+	sb.WriteString(htmlFooter)
+
+	return os.WriteFile(filename, []byte(sb.String()), 0o666)
+}
+
+const htmlHeader = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gobco condition coverage</title>
+<style>
+body { font-family: sans-serif; }
+pre { font-family: monospace; background: #f8f8f8; padding: 1em; }
+table.index td, table.index th { padding: 0 1em; text-align: left; }
+.never { background: #f4b4b4; }
+.true-only { background: #f8e49c; }
+.false-only { background: #c8d8f8; }
+.covered { background: #b8e8b0; }
+</style>
+</head>
+<body>
+<h1>Condition coverage</h1>
+<p>
+<span class="never">never evaluated</span>
+<span class="true-only">only true</span>
+<span class="false-only">only false</span>
+<span class="covered">fully covered</span>
+</p>
+`
+
+const htmlFooter = `</body>
+</html>
+`
+
+// annotateHTML returns the escaped source code,
+// with each condition wrapped in a span that describes its coverage.
+func annotateHTML(src string, conds []condition) string {
+	type span struct {
+		start, end int
+		cond       condition
+	}
+
+	lines := lineOffsets(src)
+	var spans []span
+	for _, cond := range conds {
+		start := sourceOffset(src, lines, cond.Start)
+		if start < 0 {
+			continue
+		}
+		end := sourceOffset(src, lines, cond.End)
+		if end <= start {
+			end = tokenEnd(src, start)
+		}
+		spans = append(spans, span{start, end, cond})
+	}
+
+	// Since the conditions come from the syntax tree, they are properly
+	// nested, so the outer condition comes first.
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end > spans[j].end
+	})
+
+	var sb strings.Builder
+	var open []int // the end offsets of the open spans
+	pos := 0
+	closeUntil := func(offset int) {
+		for len(open) > 0 && open[len(open)-1] <= offset {
+			end := open[len(open)-1]
+			sb.WriteString(html.EscapeString(src[pos:end]))
+			sb.WriteString("</span>")
+			pos = end
+			open = open[:len(open)-1]
+		}
+	}
+
+	for _, s := range spans {
+		closeUntil(s.start)
+		if len(open) > 0 && s.end > open[len(open)-1] {
+			continue // overlapping; should not happen
+		}
+		sb.WriteString(html.EscapeString(src[pos:s.start]))
+		sb.WriteString(fmt.Sprintf("<span class=\"%s\" title=\"%s\">",
+			s.cond.state(), html.EscapeString(s.cond.summary())))
+		pos = s.start
+		open = append(open, s.end)
+	}
+	closeUntil(len(src))
+	sb.WriteString(html.EscapeString(src[pos:]))
+
+	return sb.String()
+}
+
+// lineOffsets returns the offset of the start of each line.
+func lineOffsets(src string) []int {
+	offsets := []int{0}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			offsets = append(offsets, i+1)
+		}
+	}
+	return offsets
+}
+
+// sourceOffset returns the byte offset of the position in the source,
+// or -1 if the position is invalid.
+func sourceOffset(src string, lines []int, pos string) int {
+	p, ok := parsePosition(pos)
+	if !ok || p.line > len(lines) {
+		return -1
+	}
+	offset := lines[p.line-1] + p.col - 1
+	if offset > len(src) {
+		return -1
+	}
+	return offset
+}
+
+// tokenEnd returns the end of the identifier or keyword at offset,
+// for conditions whose end is unknown.
+func tokenEnd(src string, offset int) int {
+	end := offset
+	for end < len(src) && isIdentByte(src[end]) {
+		end++
+	}
+	if end == offset && end < len(src) {
+		end++
+	}
+	return end
+}
+
+func isIdentByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' ||
+		b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || b >= 0x80
+}
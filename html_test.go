@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_annotateHTML(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	src := "package p\n\nvar x = a < 3 && b\n"
+	conds := []condition{
+		{Start: "p.go:3:9", Code: "a < 3 && b", TrueCount: 2, FalseCount: 1, End: "p.go:3:19"},
+		{Start: "p.go:3:9", Code: "a < 3", TrueCount: 3, FalseCount: 0, End: "p.go:3:14"},
+		{Start: "p.go:3:18", Code: "b", TrueCount: 0, FalseCount: 0},
+	}
+
+	s.CheckEquals(annotateHTML(src, conds), ""+
+		"package p\n\nvar x = "+
+		"<span class=\"covered\" title=\"a &lt; 3 &amp;&amp; b: 2 times true, once false\">"+
+		"<span class=\"true-only\" title=\"a &lt; 3: 3 times true, 0 times false\">a &lt; 3</span>"+
+		" &amp;&amp; "+
+		"<span class=\"never\" title=\"b: 0 times true, 0 times false\">b</span>"+
+		"</span>\n")
+}
+
+func Test_parsePosition(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	test := func(pos string, expected position, expectedOK bool) {
+		actual, ok := parsePosition(pos)
+		s.CheckEquals(actual, expected)
+		s.CheckEquals(ok, expectedOK)
+	}
+
+	test("file.go:12:5", position{"file.go", 12, 5}, true)
+	test("C:\\dir\\file.go:1:2", position{"C:\\dir\\file.go", 1, 2}, true)
+	test("file.go:12", position{}, false)
+	test("file.go:0:5", position{}, false)
+	test("", position{}, false)
+}
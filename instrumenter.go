@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"go/ast"
 	"go/build"
+	"go/constant"
 	"go/importer"
 	"go/parser"
 	"go/printer"
 	"go/token"
 	"go/types"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
 	"runtime"
@@ -22,7 +24,20 @@ import (
 // cond is a condition from the code that is instrumented.
 type cond struct {
 	pos  string // for example "main.go:17:13"
+	end  string // for example "main.go:17:18", or "" if unknown
 	text string // for example "i > 0"
+	// Either "" for a condition,
+	// "enum" for an enum value in a switch statement,
+	// "return" for a return statement or "panic" for a call to panic.
+	kind string
+	// The class of the condition, such as "error" for 'err != nil',
+	// for filtering the output.
+	class string
+	// The function that contains the condition,
+	// such as "Func" or "Type.Method", or "" outside of functions.
+	fn string
+	// For enum values, the type of the switch tag, such as "Kind".
+	typ string
 }
 
 // exprSubst prepares to later replace '*ref' with 'expr'.
@@ -30,7 +45,9 @@ type exprSubst struct {
 	ref  *ast.Expr
 	expr ast.Expr
 	pos  token.Pos
+	end  token.Pos
 	text string
+	kind string
 }
 
 // instrumenter rewrites the code of a go package
@@ -40,15 +57,27 @@ type instrumenter struct {
 	coverTest   bool // also cover the test code
 	immediately bool // persist counts after each increment
 	listAll     bool // also list conditions that are covered
+	exits       bool // also cover return statements and calls to panic
+	program     bool // persist the counts when the main function returns
+	recordTests bool // record the tests that cover each condition
 	debugTypes  bool
 
 	fset *token.FileSet
 	pkg  map[*ast.Package]*types.Package
 	typ  map[ast.Expr]types.Type
+	val  map[ast.Expr]constant.Value
+
+	// The type information of the original code,
+	// for the details that typ and val don't cover.
+	info types.Info
 
 	// While instrumenting of a file, the current package.
 	typePkg *types.Package
 
+	// While instrumenting of a file, the current file,
+	// for referring to the imported packages.
+	file *ast.File
+
 	// Generates variable names that are unique per function.
 	varname int
 
@@ -70,8 +99,17 @@ type instrumenter struct {
 	// but not with a slice of statements.
 	stmtSubst map[ast.Stmt]ast.Stmt
 
+	// Conditions that are not part of the original code,
+	// such as the enum values that are observed in a switch statement.
+	// They are instrumented when their statement is replaced.
+	stmtConds map[ast.Stmt][]*exprSubst
+
 	hasTestMain bool
 
+	// Whether the code calls signal.Notify itself,
+	// in which case the instrumented code leaves the signals alone.
+	handlesSignals bool
+
 	// The conditions from the original code that were instrumented,
 	// from all files from fset.
 	conds []cond
@@ -113,9 +151,11 @@ func (i *instrumenter) instrument(srcDir, singleFile, dstDir string) bool {
 func (i *instrumenter) resolveTypes(pkgsMap map[string]*ast.Package) {
 	imp := importer.ForCompiler(i.fset, "source", nil)
 	conf := types.Config{Importer: imp}
-	info := types.Info{
+	i.info = types.Info{
 		Types: make(map[ast.Expr]types.TypeAndValue),
+		Uses:  make(map[*ast.Ident]types.Object),
 	}
+	info := &i.info
 
 	rememberType := func(n ast.Node) bool {
 		expr, ok := n.(ast.Expr)
@@ -127,6 +167,9 @@ func (i *instrumenter) resolveTypes(pkgsMap map[string]*ast.Package) {
 			return true
 		}
 		i.typ[expr] = tv.Type
+		if tv.Value != nil {
+			i.val[expr] = tv.Value
+		}
 		if i.debugTypes {
 			fmt.Printf("expression '%s' has type '%s'\n",
 				i.str(expr), tv.Type)
@@ -139,7 +182,7 @@ func (i *instrumenter) resolveTypes(pkgsMap map[string]*ast.Package) {
 		for _, file := range pkg.Files {
 			files = append(files, file)
 		}
-		typePkg, err := conf.Check(pkg.Name, i.fset, files, &info)
+		typePkg, err := conf.Check(pkg.Name, i.fset, files, info)
 		ok(err)
 		i.pkg[pkg] = typePkg
 		for _, f := range files {
@@ -155,7 +198,11 @@ func (i *instrumenter) instrumentFile(filename string, astFile *ast.File, dstDir
 	}
 	if isTest {
 		i.instrumentTestMain(astFile)
+		i.instrumentTests(astFile)
+	} else if i.program {
+		i.instrumentMain(astFile)
 	}
+	i.findSignalHandlers(astFile)
 
 	var out strings.Builder
 	ok(printer.Fprint(&out, i.fset, astFile))
@@ -163,6 +210,7 @@ func (i *instrumenter) instrumentFile(filename string, astFile *ast.File, dstDir
 }
 
 func (i *instrumenter) instrumentFileNode(f *ast.File) {
+	i.file = f
 	ast.Inspect(f, i.markConds)
 	ast.Inspect(f, i.findRefs)
 	ast.Inspect(f, i.prepareStmts)
@@ -194,6 +242,11 @@ func (i *instrumenter) instrumentFileNode(f *ast.File) {
 //
 // In branch coverage mode,
 // only the whole controlling condition is instrumented.
+//
+// In both modes, the comma-ok forms 'v, ok := m[k]', 't, ok := x.(T)'
+// and 'v, ok := <-ch' are marked as well,
+// as 'ok' decides the later behavior of the code
+// even if it doesn't appear in a condition.
 func (i *instrumenter) markConds(n ast.Node) bool {
 	// The order of the cases matches the order in ast.Walk.
 	switch n := n.(type) {
@@ -226,6 +279,11 @@ func (i *instrumenter) markConds(n ast.Node) bool {
 			i.marked[n] = true
 		}
 
+	case *ast.AssignStmt:
+		if len(n.Lhs) == 2 && len(n.Rhs) == 1 {
+			i.markCommaOk(n.Rhs[0])
+		}
+
 	case *ast.IfStmt:
 		i.marked[n.Cond] = true
 
@@ -238,11 +296,30 @@ func (i *instrumenter) markConds(n ast.Node) bool {
 			}
 		}
 
+	case *ast.CommClause:
+		// The receive operation in 'case v, ok := <-ch' cannot be wrapped
+		// in a function call, so only visit its subexpressions.
+		if assign, ok := n.Comm.(*ast.AssignStmt); ok {
+			for _, lhs := range assign.Lhs {
+				ast.Inspect(lhs, i.markConds)
+			}
+			ast.Inspect(assign.Rhs[0], i.markConds)
+			for _, stmt := range n.Body {
+				ast.Inspect(stmt, i.markConds)
+			}
+			return false
+		}
+
 	case *ast.ForStmt:
 		if n.Cond != nil {
 			i.marked[n.Cond] = true
 		}
 
+	case *ast.ValueSpec:
+		if len(n.Names) == 2 && len(n.Values) == 1 {
+			i.markCommaOk(n.Values[0])
+		}
+
 	case *ast.GenDecl:
 		if n.Tok == token.CONST {
 			return false
@@ -252,6 +329,21 @@ func (i *instrumenter) markConds(n ast.Node) bool {
 	return true
 }
 
+// markCommaOk marks the right-hand side of a two-valued assignment
+// if it is a map index expression, a type assertion or a channel receive.
+//
+// The type checker records the type of such an expression as a tuple,
+// which is how these expressions are distinguished
+// from function calls that return two values.
+func (i *instrumenter) markCommaOk(rhs ast.Expr) {
+	if _, isCall := unparen(rhs).(*ast.CallExpr); isCall {
+		return
+	}
+	if _, isTuple := i.typ[rhs].(*types.Tuple); isTuple {
+		i.marked[rhs] = true
+	}
+}
+
 // findRefs remembers, for each relevant expression or statement,
 // from which single location it is referenced.
 // This information is later used to replace expressions or statements
@@ -297,7 +389,7 @@ func (i *instrumenter) findRefsField(field reflect.Value) {
 			delete(i.marked, expr)
 			ref := field.Addr().Interface().(*ast.Expr)
 			i.exprSubst[expr] = &exprSubst{
-				ref, expr, expr.Pos(), i.str(expr),
+				ref, expr, expr.Pos(), expr.End(), i.str(expr), "",
 			}
 		}
 
@@ -306,7 +398,7 @@ func (i *instrumenter) findRefsField(field reflect.Value) {
 			if i.marked[expr] {
 				delete(i.marked, expr)
 				i.exprSubst[expr] = &exprSubst{
-					&val[ei], expr, expr.Pos(), i.str(expr),
+					&val[ei], expr, expr.Pos(), expr.End(), i.str(expr), "",
 				}
 			}
 		}
@@ -326,6 +418,24 @@ func (i *instrumenter) findRefsField(field reflect.Value) {
 func (i *instrumenter) prepareStmts(n ast.Node) bool {
 	switch n := n.(type) {
 
+	case *ast.ExprStmt:
+		if i.exits && i.isPanicCall(n.X) {
+			i.prepareExit(n, "panic")
+		}
+		if i.isPkgCall(n.X, "log", "Fatal", "Fatalf", "Fatalln") {
+			i.prepareFatal(n)
+		}
+
+	case *ast.CallExpr:
+		if i.isPkgCall(n, "os", "Exit") {
+			i.prepareOsExit(n)
+		}
+
+	case *ast.ReturnStmt:
+		if i.exits {
+			i.prepareExit(n, "return")
+		}
+
 	case *ast.SwitchStmt:
 		i.prepareSwitchStmt(n)
 
@@ -339,6 +449,129 @@ func (i *instrumenter) prepareStmts(n ast.Node) bool {
 	return true
 }
 
+// prepareExit prepares to count how often the statement is executed,
+// which is either a return statement or a call to panic.
+// Such a statement may mark an early exit from a function
+// that doesn't have a condition of its own,
+// for example after a 'fallthrough' or in a 'select' statement.
+//
+// The statement is replaced with the block
+//
+//	{
+//		_ = GobcoCover(idx, true)
+//		stmt
+//	}
+//
+// which is still a terminating statement.
+func (i *instrumenter) prepareExit(stmt ast.Stmt, kind string) {
+	gen := codeGenerator{stmt.Pos()}
+	use := gen.use(gen.ident("true"))
+	i.stmtConds[stmt] = append(i.stmtConds[stmt], &exprSubst{
+		&use.Rhs[0],
+		use.Rhs[0],
+		stmt.Pos(),
+		stmt.End(),
+		kind,
+		kind,
+	})
+	i.stmtSubst[stmt] = gen.block([]ast.Stmt{use, stmt})
+}
+
+// prepareOsExit transforms the call 'os.Exit(code)'
+// to 'os.Exit(GobcoFinish(code))',
+// so that the counts are persisted before the process terminates.
+//
+// In test files, the calls are transformed by instrumentTestMain.
+func (i *instrumenter) prepareOsExit(call *ast.CallExpr) {
+	if i.isTestFile() || len(call.Args) != 1 {
+		return
+	}
+	gen := codeGenerator{call.Pos()}
+	call.Args[0] = gen.callFinish(call.Args[0])
+}
+
+// prepareFatal prepares to persist the counts
+// before a call to log.Fatal, log.Fatalf or log.Fatalln,
+// which terminate the process without running deferred functions.
+//
+// The statement is replaced with the block
+//
+//	{
+//		_ = GobcoFinish(0)
+//		stmt
+//	}
+func (i *instrumenter) prepareFatal(stmt *ast.ExprStmt) {
+	if i.isTestFile() {
+		return
+	}
+	gen := codeGenerator{stmt.Pos()}
+	finish := gen.use(gen.callFinish(gen.intLit(0)))
+	i.stmtSubst[stmt] = gen.block([]ast.Stmt{finish, stmt})
+}
+
+// isPanicCall returns whether e is a call to the built-in function panic,
+// as opposed to a local function or variable of the same name.
+func (i *instrumenter) isPanicCall(e ast.Expr) bool {
+	call, ok := e.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	fn, ok := unparen(call.Fun).(*ast.Ident)
+	if !ok || fn.Name != "panic" {
+		return false
+	}
+	_, builtin := i.info.Uses[fn].(*types.Builtin)
+	return builtin
+}
+
+// isTestFile returns whether the current file contains test code.
+func (i *instrumenter) isTestFile() bool {
+	return strings.HasSuffix(i.fset.Position(i.file.Pos()).Filename, "_test.go")
+}
+
+// isPkgCall returns whether expr is a call to one of the given functions
+// from the package with the given import path,
+// based on the imports of the current file.
+func (i *instrumenter) isPkgCall(expr ast.Expr, pkgPath string, names ...string) bool {
+	call, ok := unparen(expr).(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok || pkg.Name != i.importedAs(pkgPath) {
+		return false
+	}
+	for _, name := range names {
+		if sel.Sel.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// importedAs returns the name under which the current file
+// imports the package, or "" if the package is not imported by name.
+func (i *instrumenter) importedAs(pkgPath string) string {
+	for _, imp := range i.file.Imports {
+		p, err := strconv.Unquote(imp.Path.Value)
+		ok(err)
+		if p != pkgPath {
+			continue
+		}
+		if imp.Name == nil {
+			return path.Base(p)
+		}
+		if imp.Name.Name != "_" && imp.Name.Name != "." {
+			return imp.Name.Name
+		}
+	}
+	return ""
+}
+
 func (i *instrumenter) prepareSwitchStmt(n *ast.SwitchStmt) {
 	if n.Tag == nil {
 		return // Already handled in instrumenter.markConds.
@@ -362,11 +595,15 @@ func (i *instrumenter) prepareSwitchStmt(n *ast.SwitchStmt) {
 		clause := clause.(*ast.CaseClause)
 		for j, expr := range clause.List {
 			gen := codeGenerator{expr.Pos()}
+			eql := gen.eql(tagExprName, expr)
+			i.typ[eql.X] = i.typ[n.Tag] // for classifying the condition
 			i.exprSubst[expr] = &exprSubst{
 				&clause.List[j],
-				gen.eql(tagExprName, expr),
+				eql,
 				expr.Pos(),
+				expr.End(),
 				i.strEql(n.Tag, expr),
+				"",
 			}
 			tagExprUsed = true
 		}
@@ -379,7 +616,9 @@ func (i *instrumenter) prepareSwitchStmt(n *ast.SwitchStmt) {
 	}
 	tagRef := []ast.Expr{n.Tag}
 	newBody = append(newBody, gen.defineExprs(tagExprName, tagRef))
-	if !tagExprUsed {
+	enumValues := i.prepareEnumValues(n, tagExprName)
+	newBody = append(newBody, enumValues...)
+	if !tagExprUsed && len(enumValues) == 0 {
 		newBody = append(newBody, gen.use(gen.ident(tagExprName)))
 	}
 	newBody = append(newBody, gen.switchStmt(nil, n.Body))
@@ -397,6 +636,135 @@ func (i *instrumenter) prepareSwitchStmt(n *ast.SwitchStmt) {
 	}
 }
 
+// prepareEnumValues returns statements that record
+// which of the declared constants of the tag's type
+// are observed at the tag of the switch statement.
+//
+// Only those constants whose values are not handled
+// by any of the case clauses are recorded,
+// as the handled values are already covered by the case clauses.
+func (i *instrumenter) prepareEnumValues(n *ast.SwitchStmt, tagExprName string) []ast.Stmt {
+	named, ok := i.typ[n.Tag].(*types.Named)
+	if !ok {
+		return nil
+	}
+	typePkg := named.Obj().Pkg()
+	if typePkg == nil {
+		return nil
+	}
+	consts := enumConsts(named)
+	if consts == nil {
+		return nil
+	}
+	pos := n.Tag.Pos()
+	pkgName := ""
+	if typePkg != i.typePkg {
+		name, found := i.importName(typePkg)
+		if !found || name == "" || !i.denotesPkg(name, typePkg, pos) {
+			return nil
+		}
+		pkgName = name
+	}
+
+	var handled []constant.Value
+	for _, clause := range n.Body.List {
+		for _, expr := range clause.(*ast.CaseClause).List {
+			if val := i.val[expr]; val != nil {
+				handled = append(handled, val)
+			}
+		}
+	}
+	isHandled := func(val constant.Value) bool {
+		for _, h := range handled {
+			if constant.Compare(h, token.EQL, val) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var stmts []ast.Stmt
+	for _, c := range consts {
+		if isHandled(c.Val()) {
+			continue
+		}
+		if pkgName != "" && !c.Exported() ||
+			pkgName == "" && i.lookup(c.Name(), pos) != c {
+			continue
+		}
+
+		gen := codeGenerator{pos}
+		var value ast.Expr = gen.ident(c.Name())
+		if pkgName != "" {
+			value = &ast.SelectorExpr{X: gen.ident(pkgName), Sel: gen.ident(c.Name())}
+		}
+		eql := gen.eql(tagExprName, value)
+		i.typ[eql.X] = named // for describing the value
+		use := gen.use(eql)
+		i.stmtConds[n] = append(i.stmtConds[n], &exprSubst{
+			&use.Rhs[0],
+			use.Rhs[0],
+			pos,
+			n.Tag.End(),
+			i.strEql(n.Tag, value),
+			"enum",
+		})
+		stmts = append(stmts, use)
+	}
+	return stmts
+}
+
+// enumConsts returns the constants of the named type in declaration order
+// if the type looks like an enumeration, or nil otherwise.
+//
+// An enumeration has an integer type, and its constants have distinct
+// values that lie close together, as when they are declared using iota.
+// This excludes types like time.Duration, whose constants are units,
+// fs.FileMode, whose constants are bit masks,
+// and reflect.Kind, which has alias constants.
+func enumConsts(named *types.Named) []*types.Const {
+	basic, ok := named.Underlying().(*types.Basic)
+	if !ok || basic.Info()&types.IsInteger == 0 {
+		return nil
+	}
+
+	var consts []*types.Const
+	scope := named.Obj().Pkg().Scope()
+	for _, name := range scope.Names() {
+		c, ok := scope.Lookup(name).(*types.Const)
+		if ok && types.Identical(c.Type(), named) {
+			consts = append(consts, c)
+		}
+	}
+	if len(consts) == 0 {
+		return nil
+	}
+
+	seen := map[int64]bool{}
+	var min, max int64
+	for j, c := range consts {
+		val, exact := constant.Int64Val(c.Val())
+		if !exact || seen[val] {
+			return nil
+		}
+		seen[val] = true
+		if j == 0 || val < min {
+			min = val
+		}
+		if j == 0 || val > max {
+			max = val
+		}
+	}
+	if uint64(max-min) >= 2*uint64(len(consts)) {
+		return nil
+	}
+
+	sort.SliceStable(consts, func(a, b int) bool {
+		return consts[a].Pos() < consts[b].Pos()
+	})
+	return consts
+}
+
 func (i *instrumenter) prepareTypeSwitchStmt(ts *ast.TypeSwitchStmt) {
 	gen := codeGenerator{ts.Switch}
 
@@ -418,6 +786,7 @@ func (i *instrumenter) prepareTypeSwitchStmt(ts *ast.TypeSwitchStmt) {
 	// to keep the following switch statement simple and uniform.
 	type typeTest struct {
 		pos     token.Pos
+		end     token.Pos
 		varname string
 		code    string
 	}
@@ -429,7 +798,7 @@ func (i *instrumenter) prepareTypeSwitchStmt(ts *ast.TypeSwitchStmt) {
 				tag = i.nextVarname()
 			}
 			v := i.nextVarname()
-			test := typeTest{typ.Pos(), v, i.strEql(tagExpr, typ)}
+			test := typeTest{typ.Pos(), typ.End(), v, i.strEql(tagExpr, typ)}
 			tests = append(tests, test)
 
 			posTyp := gen.reposition(typ)
@@ -470,7 +839,7 @@ func (i *instrumenter) prepareTypeSwitchStmt(ts *ast.TypeSwitchStmt) {
 
 			gen := codeGenerator{test.pos}
 			ident := gen.ident(test.varname)
-			wrapped := i.callCover(ident, test.pos, test.code)
+			wrapped := i.callCoverKind(ident, test.pos, test.end, test.code, "", "type")
 			newList = append(newList, wrapped)
 		}
 
@@ -507,13 +876,20 @@ func (i *instrumenter) replace(n ast.Node) bool {
 
 	case ast.Expr:
 		if s := i.exprSubst[n]; s != nil {
-			*s.ref = i.callCover(s.expr, s.pos, s.text)
+			if tuple, ok := i.typ[s.expr].(*types.Tuple); ok {
+				*s.ref = i.callCoverCommaOk(s.expr, tuple, s.pos, s.end, s.text)
+			} else {
+				*s.ref = i.callCover(s.expr, s.pos, s.end, s.text)
+			}
 		}
 
 	case ast.Stmt:
 		if stmt := i.stmtSubst[n]; stmt != nil {
 			*i.stmtRef[n] = stmt
 		}
+		for _, s := range i.stmtConds[n] {
+			*s.ref = i.callCoverKind(s.expr, s.pos, s.end, s.text, s.kind, s.kind)
+		}
 	}
 
 	return true
@@ -527,7 +903,15 @@ func (i *instrumenter) replace(n ast.Node) bool {
 // that is most closely related to the instrumented condition.
 // Especially for switch statements,
 // the position may differ from the expression that is wrapped.
-func (i *instrumenter) callCover(expr ast.Expr, pos token.Pos, code string) ast.Expr {
+// Likewise, end is the end of the uninstrumented code,
+// or token.NoPos if it is unknown.
+func (i *instrumenter) callCover(expr ast.Expr, pos, end token.Pos, code string) ast.Expr {
+	return i.callCoverKind(expr, pos, end, code, "", i.classify(expr))
+}
+
+// callCoverKind is like callCover,
+// but additionally records the kind and the class of the coverage point.
+func (i *instrumenter) callCoverKind(expr ast.Expr, pos, end token.Pos, code, kind, class string) ast.Expr {
 	assert(pos.IsValid(), "pos must refer to the code from before instrumentation")
 
 	start := i.fset.Position(pos)
@@ -536,13 +920,305 @@ func (i *instrumenter) callCover(expr ast.Expr, pos token.Pos, code string) ast.
 		return expr
 	}
 
-	i.conds = append(i.conds, cond{start.String(), code})
+	endPos := ""
+	if end.IsValid() {
+		endPos = i.fset.Position(end).String()
+	}
+
+	fn := i.funcName(pos)
+	typ := ""
+	if kind == "enum" {
+		typ = i.typeName(i.typ[expr.(*ast.BinaryExpr).X])
+	}
+	i.conds = append(i.conds, cond{start.String(), endPos, code, kind, class, fn, typ})
 	idx := len(i.conds) - 1
 
 	gen := codeGenerator{pos}
 	return gen.callGobcoCover(idx, expr, i.typ[expr], i.typePkg)
 }
 
+// typeName returns the name of the type as it is written in the current file,
+// such as "Kind" or "fs.FileMode".
+func (i *instrumenter) typeName(t types.Type) string {
+	return types.TypeString(t, func(pkg *types.Package) string {
+		if pkg == i.typePkg {
+			return ""
+		}
+		return pkg.Name()
+	})
+}
+
+// funcName returns the name of the function declaration that contains pos,
+// such as "Func" or "Type.Method".
+// Function literals belong to their enclosing function declaration.
+func (i *instrumenter) funcName(pos token.Pos) string {
+	for _, decl := range i.file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if ok && fn.Pos() <= pos && pos < fn.End() {
+			return funcDeclName(fn)
+		}
+	}
+	return ""
+}
+
+// funcDeclName returns the name of the function, such as "Func",
+// or "Type.Method" for methods, regardless of a pointer receiver.
+func funcDeclName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+
+	recv := fn.Recv.List[0].Type
+	for {
+		switch r := recv.(type) {
+		case *ast.StarExpr:
+			recv = r.X
+		case *ast.ParenExpr:
+			recv = r.X
+		case *ast.IndexExpr:
+			recv = r.X
+		case *ast.Ident:
+			return r.Name + "." + fn.Name.Name
+		default:
+			return fn.Name.Name
+		}
+	}
+}
+
+// classify determines the class of the condition,
+// based on its syntax and on the types of its operands.
+//
+// The classes are "error" for comparing an error with nil,
+// "nil" for other comparisons with nil,
+// "len" for comparisons involving len or cap,
+// "const" for comparisons with a constant,
+// "call" for a function call returning bool,
+// and "other" for everything else, including type conversions.
+func (i *instrumenter) classify(expr ast.Expr) string {
+	switch e := unparen(expr).(type) {
+	case *ast.BinaryExpr:
+		if e.Op.Precedence() != token.EQL.Precedence() {
+			break
+		}
+		x, y := unparen(e.X), unparen(e.Y)
+		switch {
+		case isNilIdent(y):
+			return i.classifyNil(x)
+		case isNilIdent(x):
+			return i.classifyNil(y)
+		case isLenCall(x) || isLenCall(y):
+			return "len"
+		case i.val[x] != nil || i.val[y] != nil:
+			return "const"
+		}
+	case *ast.CallExpr:
+		if !i.info.Types[e.Fun].IsType() {
+			return "call"
+		}
+	}
+	return "other"
+}
+
+// classifyNil determines the class of a comparison of x with nil.
+func (i *instrumenter) classifyNil(x ast.Expr) string {
+	errorType := types.Universe.Lookup("error").Type()
+	iface := errorType.Underlying().(*types.Interface)
+	if t := i.typ[x]; t != nil && types.Implements(t, iface) {
+		return "error"
+	}
+	return "nil"
+}
+
+// callCoverCommaOk transforms the comma-ok expression
+//
+//	m[k]
+//
+// into
+//
+//	func() (V, bool) {
+//		gobco0, gobco1 := m[k]
+//		return gobco0, GobcoCover(idx, gobco1)
+//	}()
+//
+// so that the outcome of the map lookup, type assertion or channel receive
+// is recorded at the point of the assignment.
+//
+// If the types of the values cannot be referred to from the current file,
+// the expression is returned unmodified.
+func (i *instrumenter) callCoverCommaOk(expr ast.Expr, tuple *types.Tuple, pos, end token.Pos, code string) ast.Expr {
+	valType := i.typeExpr(tuple.At(0).Type(), pos)
+	okType := i.typeExpr(tuple.At(1).Type(), pos)
+	if valType == nil || okType == nil {
+		return expr
+	}
+
+	gen := codeGenerator{pos}
+	valName := i.nextVarname()
+	okName := i.nextVarname()
+	okIdent := gen.ident(okName)
+	i.typ[okIdent] = tuple.At(1).Type()
+
+	return gen.callFuncLit(
+		[]ast.Expr{valType, okType},
+		[]ast.Stmt{
+			&ast.AssignStmt{
+				Lhs:    []ast.Expr{gen.ident(valName), gen.ident(okName)},
+				TokPos: pos,
+				Tok:    token.DEFINE,
+				Rhs:    []ast.Expr{expr},
+			},
+			&ast.ReturnStmt{
+				Return: pos,
+				Results: []ast.Expr{
+					gen.ident(valName),
+					i.callCoverKind(okIdent, pos, end, code, "", "comma-ok"),
+				},
+			},
+		})
+}
+
+// typeExpr returns an expression denoting the type t in the current file,
+// or nil if the type cannot be referred to at the given position,
+// for example because it is unexported, because its package is not
+// imported, or because one of the names in the type is shadowed.
+func (i *instrumenter) typeExpr(t types.Type, pos token.Pos) ast.Expr {
+	if !i.canReferTo(t, pos) {
+		return nil
+	}
+	qualifier := func(pkg *types.Package) string {
+		if pkg == i.typePkg {
+			return ""
+		}
+		name, _ := i.importName(pkg)
+		return name
+	}
+
+	expr, err := parser.ParseExpr(types.TypeString(t, qualifier))
+	if err != nil {
+		return nil
+	}
+	return codeGenerator{pos}.reposition(expr)
+}
+
+// canReferTo returns whether each name in the type string of t
+// denotes the same object at the given position in the current file.
+func (i *instrumenter) canReferTo(t types.Type, pos token.Pos) bool {
+	resolvesTo := func(name string, obj types.Object) bool {
+		return obj != nil && i.lookup(name, pos) == obj
+	}
+	accessible := func(obj types.Object) bool {
+		return obj.Exported() || obj.Pkg() == i.typePkg
+	}
+
+	var visit func(t types.Type) bool
+	visitTuple := func(tuple *types.Tuple) bool {
+		for j := 0; j < tuple.Len(); j++ {
+			if !visit(tuple.At(j).Type()) {
+				return false
+			}
+		}
+		return true
+	}
+	visit = func(typ types.Type) bool {
+		switch t := typ.(type) {
+		case *types.Basic:
+			return resolvesTo(t.Name(), types.Universe.Lookup(t.Name()))
+		case interface{ Obj() *types.TypeName }:
+			obj := t.Obj()
+			switch {
+			case obj.Type() != typ:
+				// An instantiated generic type.
+				return false
+			case obj.Pkg() == nil, obj.Pkg() == i.typePkg:
+				return resolvesTo(obj.Name(), obj)
+			case !obj.Exported():
+				return false
+			}
+			name, found := i.importName(obj.Pkg())
+			if !found {
+				return false
+			}
+			if name == "" {
+				return resolvesTo(obj.Name(), obj)
+			}
+			return i.denotesPkg(name, obj.Pkg(), pos)
+		case *types.Pointer:
+			return visit(t.Elem())
+		case *types.Slice:
+			return visit(t.Elem())
+		case *types.Array:
+			return visit(t.Elem())
+		case *types.Map:
+			return visit(t.Key()) && visit(t.Elem())
+		case *types.Chan:
+			return visit(t.Elem())
+		case *types.Signature:
+			return visitTuple(t.Params()) && visitTuple(t.Results())
+		case *types.Struct:
+			for j := 0; j < t.NumFields(); j++ {
+				field := t.Field(j)
+				if !accessible(field) || !visit(field.Type()) {
+					return false
+				}
+			}
+			return true
+		case *types.Interface:
+			for j := 0; j < t.NumExplicitMethods(); j++ {
+				method := t.ExplicitMethod(j)
+				if !accessible(method) || !visit(method.Type()) {
+					return false
+				}
+			}
+			for j := 0; j < t.NumEmbeddeds(); j++ {
+				if !visit(t.EmbeddedType(j)) {
+					return false
+				}
+			}
+			return true
+		}
+		return false
+	}
+	return visit(t)
+}
+
+// lookup returns the object that the name denotes
+// at the given position in the current file, or nil.
+func (i *instrumenter) lookup(name string, pos token.Pos) types.Object {
+	scope := i.typePkg.Scope().Innermost(pos)
+	if scope == nil {
+		return nil
+	}
+	_, obj := scope.LookupParent(name, pos)
+	return obj
+}
+
+// denotesPkg returns whether the name refers to the imported package
+// at the given position, that is, whether it is not shadowed.
+func (i *instrumenter) denotesPkg(name string, pkg *types.Package, pos token.Pos) bool {
+	pkgName, ok := i.lookup(name, pos).(*types.PkgName)
+	return ok && pkgName.Imported() == pkg
+}
+
+// importName returns the name under which the package is imported
+// in the current file, or "" if it is imported using a dot import.
+func (i *instrumenter) importName(pkg *types.Package) (string, bool) {
+	for _, imp := range i.file.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		ok(err)
+		switch {
+		case path != pkg.Path():
+			continue
+		case imp.Name == nil:
+			return pkg.Name(), true
+		case imp.Name.Name == ".":
+			return "", true
+		case imp.Name.Name != "_":
+			return imp.Name.Name, true
+		}
+	}
+	return pkg.Name(), false
+}
+
 // strEql returns the string representation of (lhs == rhs).
 func (i *instrumenter) strEql(lhs ast.Expr, rhs ast.Expr) string {
 	// Do not use printer.Fprint here,
@@ -587,37 +1263,207 @@ func needsParenthesesForEql(expr ast.Expr) bool {
 	return true
 }
 
+// instrumentTestMain makes a custom TestMain function persist the counts,
+// no matter whether it calls os.Exit or simply returns,
+// and no matter whether it calls m.Run itself
+// or passes m or m.Run to a helper function.
 func (i *instrumenter) instrumentTestMain(astFile *ast.File) {
-	done := false
-
-	wrap := func(n ast.Node) bool {
-		if call, ok := n.(*ast.CallExpr); ok {
-			if fn, ok := call.Fun.(*ast.SelectorExpr); ok {
-				if pkg, ok := fn.X.(*ast.Ident); ok {
-					if pkg.Name == "os" && fn.Sel.Name == "Exit" {
-						done = true
-						gen := codeGenerator{n.Pos()}
-						call.Args[0] = gen.callFinish(call.Args[0])
-					} else if fn.Sel.Name == "EtcdMain" {
-						if arg0, ok := call.Args[0].(*ast.SelectorExpr); ok && arg0.Sel.Name == "Run" {
-							done = true
-							gen := codeGenerator{n.Pos()}
-							call.Args[0] = gen.callFinishAfter(call.Args[0])
-						}
-					}
+	i.file = astFile
+
+	for _, decl := range astFile.Decls {
+		if decl, ok := decl.(*ast.FuncDecl); ok {
+			if decl.Recv == nil && decl.Name.Name == "TestMain" && decl.Body != nil {
+				i.hasTestMain = true
+
+				// Since Go 1.15, TestMain may return instead of calling
+				// os.Exit. The deferred call also persists the counts
+				// if the setup code panics.
+				gen := codeGenerator{decl.Body.Lbrace}
+				finish := &ast.DeferStmt{
+					Defer: gen.pos,
+					Call:  gen.callFinish(gen.intLit(0)).(*ast.CallExpr),
 				}
+				decl.Body.List = append([]ast.Stmt{finish}, decl.Body.List...)
+			}
+		}
+	}
+
+	// The helper functions that run the tests or exit
+	// may be anywhere in the test files.
+	i.wrapTestMainRun(astFile)
+	i.wrapTestMainExit(astFile)
+}
+
+// wrapTestMainRun transforms each call 'm.Run()' to 'GobcoFinish(m.Run())',
+// and each method value 'm.Run', for example when it is passed to a helper
+// function, to 'func() int { return GobcoFinish(m.Run()) }',
+// for each expression 'm' of type '*testing.M'.
+func (i *instrumenter) wrapTestMainRun(n ast.Node) {
+	isRun := func(expr ast.Expr) bool {
+		sel, ok := expr.(*ast.SelectorExpr)
+		return ok && sel.Sel.Name == "Run" && isTestingM(i.typ[sel.X])
+	}
+
+	// Collect the references first,
+	// to not visit the generated code again.
+	refs := exprRefs(n)
+
+	wrapped := map[ast.Expr]bool{}
+	for _, ref := range refs {
+		expr := *ref
+		gen := codeGenerator{expr.Pos()}
+		if call, ok := expr.(*ast.CallExpr); ok && isRun(call.Fun) {
+			wrapped[call.Fun] = true
+			*ref = gen.callFinish(call)
+		} else if isRun(expr) && !wrapped[expr] {
+			*ref = gen.callFinishAfter(expr)
+		}
+	}
+}
+
+// isTestingM returns whether the type is '*testing.M'.
+func isTestingM(t types.Type) bool {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "testing" && obj.Name() == "M"
+}
+
+// wrapTestMainExit transforms each call 'os.Exit(code)'
+// to 'os.Exit(GobcoFinish(code))',
+// to persist the counts from the code that runs after m.Run,
+// as well as from helper processes that exit early.
+func (i *instrumenter) wrapTestMainExit(n ast.Node) {
+	ast.Inspect(n, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && i.isPkgCall(call, "os", "Exit") {
+			if len(call.Args) == 1 && !isFinishCall(call.Args[0]) {
+				gen := codeGenerator{call.Pos()}
+				call.Args[0] = gen.callFinish(call.Args[0])
 			}
 		}
 		return true
+	})
+}
+
+// instrumentTests inserts deferred calls at the beginning
+// of each function that has a '*testing.T' parameter,
+// which includes the test functions and the subtests.
+//
+// The call 'defer GobcoRecover()' persists the counts when the test panics,
+// as the panic terminates the process before TestMain can persist them.
+//
+// With -record-tests, the call 'defer GobcoTest(t)()' lets the runtime
+// know which tests cover which conditions.
+func (i *instrumenter) instrumentTests(astFile *ast.File) {
+	i.file = astFile
+
+	instrument := func(typ *ast.FuncType, body *ast.BlockStmt) {
+		t := i.testingParam(typ, "T")
+		if t == "" || body == nil {
+			return
+		}
+		gen := codeGenerator{body.Lbrace}
+		var stmts []ast.Stmt
+		if i.recordTests {
+			stmts = append(stmts, &ast.DeferStmt{
+				Defer: gen.pos,
+				Call: &ast.CallExpr{
+					Fun: &ast.CallExpr{
+						Fun:    gen.ident("GobcoTest"),
+						Lparen: gen.pos,
+						Args:   []ast.Expr{gen.ident(t)},
+						Rparen: gen.pos,
+					},
+					Lparen: gen.pos,
+					Rparen: gen.pos,
+				},
+			})
+		}
+		stmts = append(stmts, &ast.DeferStmt{
+			Defer: gen.pos,
+			Call: &ast.CallExpr{
+				Fun:    gen.ident("GobcoRecover"),
+				Lparen: gen.pos,
+				Rparen: gen.pos,
+			},
+		})
+		body.List = append(stmts, body.List...)
+	}
+
+	ast.Inspect(astFile, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			instrument(n.Type, n.Body)
+		case *ast.FuncLit:
+			instrument(n.Type, n.Body)
+		}
+		return true
+	})
+}
+
+// testingParam returns the name of the parameter
+// of type '*testing.T' or '*testing.M', depending on typeName,
+// or "" if there is no such named parameter.
+func (i *instrumenter) testingParam(typ *ast.FuncType, typeName string) string {
+	if typ.Params == nil {
+		return "" // as in the code generated by wrapTestMainRun
+	}
+	testing := i.importedAs("testing")
+	for _, field := range typ.Params.List {
+		star, ok := field.Type.(*ast.StarExpr)
+		if !ok {
+			continue
+		}
+		sel, ok := star.X.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != typeName {
+			continue
+		}
+		pkg, ok := sel.X.(*ast.Ident)
+		if ok && pkg.Name == testing && len(field.Names) == 1 {
+			if name := field.Names[0].Name; name != "_" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// findSignalHandlers remembers whether the code calls signal.Notify,
+// as the instrumented code must then not interfere with the signals,
+// see flushOnSignal in templates/gobco_fixed.go.
+func (i *instrumenter) findSignalHandlers(astFile *ast.File) {
+	i.file = astFile
+	ast.Inspect(astFile, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && i.isPkgCall(call, "os/signal", "Notify") {
+			i.handlesSignals = true
+		}
+		return true
+	})
+}
+
+// instrumentMain inserts 'defer GobcoFinish(0)' at the beginning
+// of the main function,
+// so that an instrumented program persists its counts when it terminates.
+func (i *instrumenter) instrumentMain(astFile *ast.File) {
+	if astFile.Name.Name != "main" {
+		return
 	}
 
 	for _, decl := range astFile.Decls {
 		if decl, ok := decl.(*ast.FuncDecl); ok {
-			if decl.Recv == nil && decl.Name.Name == "TestMain" {
-				i.hasTestMain = true
-
-				ast.Inspect(decl.Body, wrap)
-				assert(done, "gobco can only handle TestMain with explicit call to os.Exit")
+			if decl.Recv == nil && decl.Name.Name == "main" && decl.Body != nil {
+				gen := codeGenerator{decl.Body.Lbrace}
+				finish := &ast.DeferStmt{
+					Defer: gen.pos,
+					Call:  gen.callFinish(gen.intLit(0)).(*ast.CallExpr),
+				}
+				decl.Body.List = append([]ast.Stmt{finish}, decl.Body.List...)
 			}
 		}
 	}
@@ -629,6 +1475,12 @@ var fixedTemplate string
 //go:embed templates/gobco_no_testmain_test.go
 var noTestMainTemplate string
 
+//go:embed templates/gobco_unix.go
+var unixTemplate string
+
+//go:embed templates/gobco_other.go
+var otherTemplate string
+
 func (i *instrumenter) writeGobcoFiles(tmpDir string, pkgs []*ast.Package) {
 	pkgname := pkgs[0].Name
 	fixPkgname := func(str string) string {
@@ -636,6 +1488,13 @@ func (i *instrumenter) writeGobcoFiles(tmpDir string, pkgs []*ast.Package) {
 		return strings.Replace(str, "package main\n", "package "+pkgname+"\n", 1)
 	}
 	writeFile(filepath.Join(tmpDir, "gobco_fixed.go"), fixPkgname(fixedTemplate))
+	// The instrumented code may be built for another operating system,
+	// as in 'GOOS=windows gobco build'.
+	osTemplate := otherTemplate
+	if isUnix(build.Default.GOOS) {
+		osTemplate = unixTemplate
+	}
+	writeFile(filepath.Join(tmpDir, "gobco_os.go"), fixPkgname(osTemplate))
 	i.writeGobcoGo(filepath.Join(tmpDir, "gobco_variable.go"), pkgname)
 
 	if !i.hasTestMain {
@@ -645,6 +1504,19 @@ func (i *instrumenter) writeGobcoFiles(tmpDir string, pkgs []*ast.Package) {
 	i.writeGobcoBlackBox(pkgs, tmpDir)
 }
 
+// isUnix returns whether the instrumented code can map the counts file
+// into memory and lock the stats file on the given operating system,
+// see templates/gobco_unix.go.
+// Solaris cannot lock files using flock.
+func isUnix(goos string) bool {
+	switch goos {
+	case "android", "darwin", "dragonfly", "freebsd", "illumos", "ios",
+		"linux", "netbsd", "openbsd":
+		return true
+	}
+	return false
+}
+
 func (i *instrumenter) writeGobcoGo(filename, pkgname string) {
 	var sb strings.Builder
 
@@ -653,15 +1525,19 @@ func (i *instrumenter) writeGobcoGo(filename, pkgname string) {
 	sb.WriteString("var gobcoOpts = gobcoOptions{\n")
 	sb.WriteString(fmt.Sprintf("\timmediately: %v,\n", i.immediately))
 	sb.WriteString(fmt.Sprintf("\tlistAll:     %v,\n", i.listAll))
+	sb.WriteString(fmt.Sprintf("\trecordTests: %v,\n", i.recordTests))
+	sb.WriteString(fmt.Sprintf("\tsignals:     %v,\n", !i.program && !i.handlesSignals))
+	sb.WriteString(fmt.Sprintf("\tprogram:     %v,\n", i.program))
 	sb.WriteString("}\n")
 	sb.WriteString("\n")
 	sb.WriteString("var gobcoCounts = gobcoStats{\n")
 	sb.WriteString("\tconds: []gobcoCond{\n")
 	for _, cond := range i.conds {
-		sb.WriteString(fmt.Sprintf("\t\t{%q, %q, 0, 0},\n",
-			cond.pos, cond.text))
+		sb.WriteString(fmt.Sprintf("\t\t{%q, %q, 0, 0, %q, %q, nil, nil, 0, 0, %q, %q, %q},\n",
+			cond.pos, cond.text, cond.kind, cond.class, cond.end, cond.fn, cond.typ))
 	}
 	sb.WriteString("\t},\n")
+	sb.WriteString(fmt.Sprintf("\tcounts: make([]int64, %d),\n", 2*len(i.conds)))
 	sb.WriteString("}\n")
 
 	writeFile(filename, sb.String())
@@ -708,6 +1584,17 @@ func (i *instrumenter) writeGobcoBlackBox(pkgs []*ast.Package, dstDir string) {
 		"\n" +
 		"func GobcoFinish(code int) int {\n" +
 		"\t" + "return " + pkgName + ".GobcoFinish(code)\n" +
+		"}\n" +
+		"\n" +
+		"func GobcoRecover() {\n" +
+		"\t" + "if r := recover(); r != nil {\n" +
+		"\t\t" + pkgName + ".GobcoFinish(0)\n" +
+		"\t\t" + "panic(r)\n" +
+		"\t" + "}\n" +
+		"}\n" +
+		"\n" +
+		"func GobcoTest(t interface{ Name() string }) func() {\n" +
+		"\t" + "return " + pkgName + ".GobcoTest(t)\n" +
 		"}\n"
 
 	writeFile(filepath.Join(dstDir, "gobco_bridge_test.go"), text)
@@ -791,6 +1678,27 @@ func (gen codeGenerator) callFinishAfter(run ast.Expr) ast.Expr {
 	}
 }
 
+// callFuncLit returns a call to a function literal without parameters
+// that returns values of the given types.
+func (gen codeGenerator) callFuncLit(results []ast.Expr, body []ast.Stmt) ast.Expr {
+	var fields []*ast.Field
+	for _, result := range results {
+		fields = append(fields, &ast.Field{Type: result})
+	}
+	return &ast.CallExpr{
+		Fun: &ast.FuncLit{
+			Type: &ast.FuncType{
+				Func:    gen.pos,
+				Params:  &ast.FieldList{Opening: gen.pos, Closing: gen.pos},
+				Results: &ast.FieldList{Opening: gen.pos, List: fields, Closing: gen.pos},
+			},
+			Body: gen.block(body),
+		},
+		Lparen: gen.pos,
+		Rparen: gen.pos,
+	}
+}
+
 func (gen codeGenerator) callFinish(arg ast.Expr) ast.Expr {
 	return &ast.CallExpr{
 		Fun:      gen.ident("GobcoFinish"),
@@ -866,6 +1774,14 @@ func (gen codeGenerator) defineIsType(lhs string, rhs string, typ ast.Expr) ast.
 	}
 }
 
+func (gen codeGenerator) intLit(n int) *ast.BasicLit {
+	return &ast.BasicLit{
+		ValuePos: gen.pos,
+		Kind:     token.INT,
+		Value:    strconv.Itoa(n),
+	}
+}
+
 func (gen codeGenerator) use(rhs ast.Expr) *ast.AssignStmt {
 	return &ast.AssignStmt{
 		Lhs:    []ast.Expr{gen.ident("_")},
@@ -989,13 +1905,67 @@ func forEachFile(pkg *ast.Package, action func(string, *ast.File)) {
 }
 
 func isNilIdent(e ast.Expr) bool {
-again:
-	if p, ok := e.(*ast.ParenExpr); ok {
+	ident, ok := unparen(e).(*ast.Ident)
+	return ok && ident.Name == "nil"
+}
+
+func isFinishCall(e ast.Expr) bool {
+	call, ok := e.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	fn, ok := call.Fun.(*ast.Ident)
+	return ok && fn.Name == "GobcoFinish"
+}
+
+// exprRefs returns the references to all expressions in the subtree of n,
+// in the order in which ast.Inspect visits them.
+func exprRefs(n ast.Node) []*ast.Expr {
+	var refs []*ast.Expr
+	ast.Inspect(n, func(n ast.Node) bool {
+		if n == nil {
+			return true
+		}
+		node := reflect.ValueOf(n)
+		if node.Kind() != reflect.Ptr || node.Elem().Kind() != reflect.Struct {
+			return true
+		}
+		str := node.Elem()
+		for fi, nf := 0, str.NumField(); fi < nf; fi++ {
+			field := str.Field(fi)
+			switch val := field.Interface().(type) {
+			case ast.Expr:
+				if field.Type() == reflect.TypeOf((*ast.Expr)(nil)).Elem() {
+					refs = append(refs, field.Addr().Interface().(*ast.Expr))
+				}
+			case []ast.Expr:
+				for ei := range val {
+					refs = append(refs, &val[ei])
+				}
+			}
+		}
+		return true
+	})
+	return refs
+}
+
+func isLenCall(e ast.Expr) bool {
+	call, ok := e.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	fn, ok := call.Fun.(*ast.Ident)
+	return ok && (fn.Name == "len" || fn.Name == "cap")
+}
+
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
 		e = p.X
-		goto again
 	}
-	ident, ok := e.(*ast.Ident)
-	return ok && ident.Name == "nil"
 }
 
 func shouldBuild(filename string) bool {
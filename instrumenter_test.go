@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/parser"
 	"go/printer"
 	"go/token"
@@ -10,6 +11,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -80,24 +82,7 @@ func Test_instrumenter(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		i := instrumenter{
-			branch,
-			false,
-			false,
-			false,
-			false,
-			fset,
-			map[*ast.Package]*types.Package{},
-			map[ast.Expr]types.Type{},
-			nil,
-			0,
-			map[ast.Expr]bool{},
-			map[ast.Expr]*exprSubst{},
-			map[ast.Stmt]*ast.Stmt{},
-			map[ast.Stmt]ast.Stmt{},
-			false,
-			nil,
-		}
+		i := newTestInstrumenter(fset, branch)
 		fileName := filepath.Clean(base + ".go")
 		f := pkgs["instrumenter"].Files[fileName]
 		assert(f != nil, fileName)
@@ -136,3 +121,277 @@ func Test_instrumenter(t *testing.T) {
 		})
 	}
 }
+
+func newTestInstrumenter(fset *token.FileSet, branch bool) *instrumenter {
+	return &instrumenter{
+		branch,
+		false,
+		false,
+		false,
+		false,
+		false,
+		false,
+		false,
+		fset,
+		map[*ast.Package]*types.Package{},
+		map[ast.Expr]types.Type{},
+		map[ast.Expr]constant.Value{},
+		types.Info{},
+		nil,
+		nil,
+		0,
+		map[ast.Expr]bool{},
+		map[ast.Expr]*exprSubst{},
+		map[ast.Stmt]*ast.Stmt{},
+		map[ast.Stmt]ast.Stmt{},
+		map[ast.Stmt][]*exprSubst{},
+		false,
+		false,
+		nil,
+	}
+}
+
+func Test_instrumenter_classify(t *testing.T) {
+	src := "" +
+		"package classify\n" +
+		"\n" +
+		"type flag bool\n" +
+		"\n" +
+		"func f(err error, p *int, s []int, i int, ok func() bool, fl flag) {\n" +
+		"\t_ = err != nil\n" +
+		"\t_ = nil == p\n" +
+		"\t_ = len(s) > 0\n" +
+		"\t_ = i == cap(s)\n" +
+		"\t_ = i == 5\n" +
+		"\t_ = ok()\n" +
+		"\t_ = i == *p\n" +
+		"\t_ = bool(fl)\n" +
+		"}\n"
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "classify.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := &ast.Package{Name: "classify", Files: map[string]*ast.File{"classify.go": f}}
+	i := newTestInstrumenter(fset, false)
+	i.resolveTypes(map[string]*ast.Package{"classify": pkg})
+
+	var actual []string
+	for _, stmt := range f.Decls[1].(*ast.FuncDecl).Body.List {
+		expr := stmt.(*ast.AssignStmt).Rhs[0]
+		actual = append(actual, i.str(expr)+": "+i.classify(expr))
+	}
+
+	expected := []string{
+		"err != nil: error",
+		"nil == p: nil",
+		"len(s) > 0: len",
+		"i == cap(s): len",
+		"i == 5: const",
+		"ok(): call",
+		"i == *p: other",
+		"bool(fl): other",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func Test_instrumenter_funcName(t *testing.T) {
+	src := "" +
+		"package p\n" +
+		"\n" +
+		"var v = true\n" +
+		"\n" +
+		"func F() { _ = func() bool { return true } }\n" +
+		"\n" +
+		"func (T) M() {}\n" +
+		"\n" +
+		"func (t *T) P() {}\n"
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := newTestInstrumenter(fset, false)
+	i.file = f
+
+	test := func(offset int, expected string) {
+		pos := fset.File(f.Pos()).Pos(offset)
+		if actual := i.funcName(pos); actual != expected {
+			t.Errorf("expected %q for %q, got %q", expected, src[offset:], actual)
+		}
+	}
+
+	test(strings.Index(src, "true\n"), "")
+	test(strings.Index(src, "return"), "F")
+	test(strings.Index(src, "M()"), "T.M")
+	test(strings.Index(src, "P()"), "T.P")
+}
+
+func Test_instrumenter_condEnd(t *testing.T) {
+	src := "" +
+		"package end\n" +
+		"\n" +
+		"type Kind int\n" +
+		"\n" +
+		"const (\n" +
+		"\tKindA Kind = iota\n" +
+		"\tKindB\n" +
+		")\n" +
+		"\n" +
+		"func f(m map[int]int, x interface{}, kind Kind) int {\n" +
+		"\tif _, ok := m[1]; ok {\n" +
+		"\t\tpanic(\"ok\")\n" +
+		"\t}\n" +
+		"\tswitch x.(type) {\n" +
+		"\tcase int:\n" +
+		"\t}\n" +
+		"\tswitch kind {\n" +
+		"\tcase KindA:\n" +
+		"\t}\n" +
+		"\treturn 0\n" +
+		"}\n"
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "end.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgs := map[string]*ast.Package{
+		"end": {Name: "end", Files: map[string]*ast.File{"end.go": f}},
+	}
+
+	i := newTestInstrumenter(fset, false)
+	i.exits = true
+	i.resolveTypes(pkgs)
+	i.typePkg = i.pkg[pkgs["end"]]
+	i.instrumentFileNode(f)
+
+	var actual []string
+	for _, cond := range i.conds {
+		actual = append(actual, fmt.Sprintf("%s-%s: %s %q",
+			cond.pos, strings.TrimPrefix(cond.end, "end.go:"), cond.kind, cond.text))
+	}
+	expected := []string{
+		"end.go:15:7-15:10:  \"x.(type) == int\"",
+		"end.go:11:14-11:18:  \"m[1]\"",
+		"end.go:11:20-11:22:  \"ok\"",
+		"end.go:12:3-12:14: panic \"panic\"",
+		"end.go:17:9-17:13: enum \"kind == KindB\"",
+		"end.go:18:7-18:12:  \"kind == KindA\"",
+		"end.go:20:2-20:10: return \"return\"",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func Test_instrumenter_instrumentTestMain(t *testing.T) {
+	test := func(decls, expected string) {
+		src := "" +
+			"package main\n" +
+			"\n" +
+			"import (\n" +
+			"\t\"os\"\n" +
+			"\t\"testing\"\n" +
+			")\n" +
+			"\n" +
+			"var _ = os.Args\n" +
+			"\n" +
+			decls
+
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "main_test.go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkgs := map[string]*ast.Package{
+			"main": {Name: "main", Files: map[string]*ast.File{"main_test.go": f}},
+		}
+		i := newTestInstrumenter(fset, false)
+		i.resolveTypes(pkgs)
+		i.typePkg = i.pkg[pkgs["main"]]
+		i.instrumentTestMain(f)
+
+		var sb strings.Builder
+		for _, decl := range f.Decls[2:] {
+			err = printer.Fprint(&sb, fset, decl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sb.WriteString("\n")
+		}
+		if sb.String() != expected {
+			t.Errorf("expected\n%s\ngot\n%s", expected, sb.String())
+		}
+		if !i.hasTestMain {
+			t.Errorf("expected hasTestMain")
+		}
+	}
+
+	test(
+		"func TestMain(m *testing.M) {\n"+
+			"\tos.Exit(m.Run())\n"+
+			"}\n",
+		"func TestMain(m *testing.M) {\n"+
+			"\tdefer GobcoFinish(0)\n"+
+			"\tos.Exit(GobcoFinish(m.Run()))\n"+
+			"}\n")
+
+	test(
+		"func TestMain(m *testing.M) {\n"+
+			"\tcode := m.Run()\n"+
+			"\tos.Exit(code)\n"+
+			"}\n",
+		"func TestMain(m *testing.M) {\n"+
+			"\tdefer GobcoFinish(0)\n"+
+			"\tcode := GobcoFinish(m.Run())\n"+
+			"\tos.Exit(GobcoFinish(code))\n"+
+			"}\n")
+
+	test(
+		"func TestMain(m *testing.M) {\n"+
+			"\tm.Run()\n"+
+			"}\n",
+		"func TestMain(m *testing.M) {\n"+
+			"\tdefer GobcoFinish(0)\n"+
+			"\tGobcoFinish(m.Run())\n"+
+			"}\n")
+
+	test(
+		"func TestMain(m *testing.M) {\n"+
+			"\thelper(m.Run)\n"+
+			"}\n"+
+			"func helper(run func() int) {\n"+
+			"\trun()\n"+
+			"}\n",
+		"func TestMain(m *testing.M) {\n"+
+			"\tdefer GobcoFinish(0)\n"+
+			"\thelper(func() int { return GobcoFinish(m.Run()) })\n"+
+			"}\n"+
+			"func helper(run func() int) {\n"+
+			"\trun()\n"+
+			"}\n")
+
+	// Some projects pass m to a helper function,
+	// which runs the tests and exits.
+	test(
+		"func TestMain(m *testing.M) {\n"+
+			"\tverifyTestMain(m)\n"+
+			"}\n"+
+			"func verifyTestMain(tm *testing.M) {\n"+
+			"\tcode := tm.Run()\n"+
+			"\tos.Exit(code)\n"+
+			"}\n",
+		"func TestMain(m *testing.M) {\n"+
+			"\tdefer GobcoFinish(0)\n"+
+			"\tverifyTestMain(m)\n"+
+			"}\n"+
+			"func verifyTestMain(tm *testing.M) {\n"+
+			"\tcode := GobcoFinish(tm.Run())\n"+
+			"\tos.Exit(GobcoFinish(code))\n"+
+			"}\n")
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// jsonReportVersion is incremented on each incompatible change
+// to the format of the JSON report.
+// Adding fields is not an incompatible change.
+const jsonReportVersion = 1
+
+// jsonReport is the report from '-format json',
+// intended as a stable input for other tools.
+// In contrast to the stats file, which only lists the conditions,
+// it contains aggregates for each package, file and function.
+type jsonReport struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	Metadata      jsonMetadata    `json:"metadata"`
+	Totals        jsonCounts      `json:"totals"`
+	Packages      []jsonPackage   `json:"packages"`
+	Files         []jsonFile      `json:"files"`
+	Functions     []jsonFunction  `json:"functions"`
+	Conditions    []jsonCondition `json:"conditions"`
+}
+
+type jsonMetadata struct {
+	Tool      string `json:"tool"`    // always "gobco"
+	Version   string `json:"version"` // the gobco version
+	Mode      string `json:"mode"`    // "condition" or "branch"
+	Generated string `json:"generated"`
+}
+
+// jsonCounts counts the outcomes of the conditions.
+// A condition has two outcomes, true and false.
+// An enum value, a return statement or a call to panic
+// has a single outcome.
+type jsonCounts struct {
+	Conditions int     `json:"conditions"`
+	Covered    int     `json:"covered"`
+	Total      int     `json:"total"`
+	Percent    float64 `json:"percent"`
+}
+
+func (c *jsonCounts) add(cond condition) {
+	covered, total := cond.outcomes()
+	c.Conditions++
+	c.Covered += covered
+	c.Total += total
+	c.Percent = 100 * rate(c.Covered, c.Total)
+}
+
+type jsonPackage struct {
+	Name string `json:"name"` // the directory of the package
+	jsonCounts
+}
+
+type jsonFile struct {
+	Name    string `json:"name"`
+	Package string `json:"package"`
+	jsonCounts
+}
+
+type jsonFunction struct {
+	Name string `json:"name"` // such as "Func" or "Type.Method"
+	File string `json:"file"`
+	jsonCounts
+}
+
+type jsonCondition struct {
+	Start            string   `json:"start"`
+	End              string   `json:"end,omitempty"`
+	Code             string   `json:"code"`
+	Kind             string   `json:"kind,omitempty"`
+	Class            string   `json:"class,omitempty"`
+	Func             string   `json:"func,omitempty"`
+	TrueCount        int      `json:"trueCount"`
+	FalseCount       int      `json:"falseCount"`
+	ForcedTrueCount  int      `json:"forcedTrueCount,omitempty"`
+	ForcedFalseCount int      `json:"forcedFalseCount,omitempty"`
+	TrueTests        []string `json:"trueTests,omitempty"`
+	FalseTests       []string `json:"falseTests,omitempty"`
+}
+
+// writeJSONReport writes the conditions and their aggregates as JSON.
+func writeJSONReport(w io.Writer, conds []condition, branch bool, now time.Time) error {
+	mode := "condition"
+	if branch {
+		mode = "branch"
+	}
+
+	report := jsonReport{
+		SchemaVersion: jsonReportVersion,
+		Metadata: jsonMetadata{
+			"gobco",
+			version,
+			mode,
+			now.UTC().Format(time.RFC3339),
+		},
+		Packages:   []jsonPackage{},
+		Files:      []jsonFile{},
+		Functions:  []jsonFunction{},
+		Conditions: []jsonCondition{},
+	}
+
+	pkgIndex := map[string]int{}
+	for _, file := range condsByFile(conds) {
+		name := filepath.ToSlash(file.name)
+		pkgName := filepath.ToSlash(filepath.Dir(file.name))
+
+		i, ok := pkgIndex[pkgName]
+		if !ok {
+			i = len(report.Packages)
+			pkgIndex[pkgName] = i
+			report.Packages = append(report.Packages, jsonPackage{Name: pkgName})
+		}
+
+		f := jsonFile{Name: name, Package: pkgName}
+		funcIndex := map[string]int{}
+		var funcs []jsonFunction
+		for _, cond := range file.conds {
+			report.Totals.add(cond)
+			report.Packages[i].add(cond)
+			f.add(cond)
+
+			if cond.Func != "" {
+				j, ok := funcIndex[cond.Func]
+				if !ok {
+					j = len(funcs)
+					funcIndex[cond.Func] = j
+					funcs = append(funcs, jsonFunction{Name: cond.Func, File: name})
+				}
+				funcs[j].add(cond)
+			}
+
+			report.Conditions = append(report.Conditions, jsonCondition{
+				filepath.ToSlash(cond.Start),
+				filepath.ToSlash(cond.End),
+				cond.Code,
+				cond.Kind,
+				cond.Class,
+				cond.Func,
+				cond.TrueCount,
+				cond.FalseCount,
+				cond.ForcedTrueCount,
+				cond.ForcedFalseCount,
+				cond.TrueTests,
+				cond.FalseTests,
+			})
+		}
+		report.Files = append(report.Files, f)
+
+		sort.Slice(funcs, func(i, j int) bool { return funcs[i].Name < funcs[j].Name })
+		report.Functions = append(report.Functions, funcs...)
+	}
+
+	sort.Slice(report.Packages, func(i, j int) bool {
+		return report.Packages[i].Name < report.Packages[j].Name
+	})
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(report)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_writeJSONReport(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	conds := []condition{
+		{Start: "pkg/a.go:3:5", Code: "a", TrueCount: 2, FalseCount: 1, FalseTests: []string{"TestA"}, End: "pkg/a.go:3:6", Func: "F"},
+		{Start: "pkg/a.go:7:5", Code: "b", TrueCount: 0, FalseCount: 0, End: "pkg/a.go:7:6", Func: "T.M"},
+		{Start: "pkg/sub/b.go:4:2", Code: "return 0", TrueCount: 1, FalseCount: 0, Kind: "return", Class: "return", Func: "G"},
+	}
+
+	var sb strings.Builder
+	err := writeJSONReport(&sb, conds, false, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	s.CheckEquals(err, nil)
+	s.CheckEquals(sb.String(), ""+
+		"{\n"+
+		"\t\"schemaVersion\": 1,\n"+
+		"\t\"metadata\": {\n"+
+		"\t\t\"tool\": \"gobco\",\n"+
+		"\t\t\"version\": \""+version+"\",\n"+
+		"\t\t\"mode\": \"condition\",\n"+
+		"\t\t\"generated\": \"2024-01-02T03:04:05Z\"\n"+
+		"\t},\n"+
+		"\t\"totals\": {\n"+
+		"\t\t\"conditions\": 3,\n"+
+		"\t\t\"covered\": 3,\n"+
+		"\t\t\"total\": 5,\n"+
+		"\t\t\"percent\": 60\n"+
+		"\t},\n"+
+		"\t\"packages\": [\n"+
+		"\t\t{\n"+
+		"\t\t\t\"name\": \"pkg\",\n"+
+		"\t\t\t\"conditions\": 2,\n"+
+		"\t\t\t\"covered\": 2,\n"+
+		"\t\t\t\"total\": 4,\n"+
+		"\t\t\t\"percent\": 50\n"+
+		"\t\t},\n"+
+		"\t\t{\n"+
+		"\t\t\t\"name\": \"pkg/sub\",\n"+
+		"\t\t\t\"conditions\": 1,\n"+
+		"\t\t\t\"covered\": 1,\n"+
+		"\t\t\t\"total\": 1,\n"+
+		"\t\t\t\"percent\": 100\n"+
+		"\t\t}\n"+
+		"\t],\n"+
+		"\t\"files\": [\n"+
+		"\t\t{\n"+
+		"\t\t\t\"name\": \"pkg/a.go\",\n"+
+		"\t\t\t\"package\": \"pkg\",\n"+
+		"\t\t\t\"conditions\": 2,\n"+
+		"\t\t\t\"covered\": 2,\n"+
+		"\t\t\t\"total\": 4,\n"+
+		"\t\t\t\"percent\": 50\n"+
+		"\t\t},\n"+
+		"\t\t{\n"+
+		"\t\t\t\"name\": \"pkg/sub/b.go\",\n"+
+		"\t\t\t\"package\": \"pkg/sub\",\n"+
+		"\t\t\t\"conditions\": 1,\n"+
+		"\t\t\t\"covered\": 1,\n"+
+		"\t\t\t\"total\": 1,\n"+
+		"\t\t\t\"percent\": 100\n"+
+		"\t\t}\n"+
+		"\t],\n"+
+		"\t\"functions\": [\n"+
+		"\t\t{\n"+
+		"\t\t\t\"name\": \"F\",\n"+
+		"\t\t\t\"file\": \"pkg/a.go\",\n"+
+		"\t\t\t\"conditions\": 1,\n"+
+		"\t\t\t\"covered\": 2,\n"+
+		"\t\t\t\"total\": 2,\n"+
+		"\t\t\t\"percent\": 100\n"+
+		"\t\t},\n"+
+		"\t\t{\n"+
+		"\t\t\t\"name\": \"T.M\",\n"+
+		"\t\t\t\"file\": \"pkg/a.go\",\n"+
+		"\t\t\t\"conditions\": 1,\n"+
+		"\t\t\t\"covered\": 0,\n"+
+		"\t\t\t\"total\": 2,\n"+
+		"\t\t\t\"percent\": 0\n"+
+		"\t\t},\n"+
+		"\t\t{\n"+
+		"\t\t\t\"name\": \"G\",\n"+
+		"\t\t\t\"file\": \"pkg/sub/b.go\",\n"+
+		"\t\t\t\"conditions\": 1,\n"+
+		"\t\t\t\"covered\": 1,\n"+
+		"\t\t\t\"total\": 1,\n"+
+		"\t\t\t\"percent\": 100\n"+
+		"\t\t}\n"+
+		"\t],\n"+
+		"\t\"conditions\": [\n"+
+		"\t\t{\n"+
+		"\t\t\t\"start\": \"pkg/a.go:3:5\",\n"+
+		"\t\t\t\"end\": \"pkg/a.go:3:6\",\n"+
+		"\t\t\t\"code\": \"a\",\n"+
+		"\t\t\t\"func\": \"F\",\n"+
+		"\t\t\t\"trueCount\": 2,\n"+
+		"\t\t\t\"falseCount\": 1,\n"+
+		"\t\t\t\"falseTests\": [\n"+
+		"\t\t\t\t\"TestA\"\n"+
+		"\t\t\t]\n"+
+		"\t\t},\n"+
+		"\t\t{\n"+
+		"\t\t\t\"start\": \"pkg/a.go:7:5\",\n"+
+		"\t\t\t\"end\": \"pkg/a.go:7:6\",\n"+
+		"\t\t\t\"code\": \"b\",\n"+
+		"\t\t\t\"func\": \"T.M\",\n"+
+		"\t\t\t\"trueCount\": 0,\n"+
+		"\t\t\t\"falseCount\": 0\n"+
+		"\t\t},\n"+
+		"\t\t{\n"+
+		"\t\t\t\"start\": \"pkg/sub/b.go:4:2\",\n"+
+		"\t\t\t\"code\": \"return 0\",\n"+
+		"\t\t\t\"kind\": \"return\",\n"+
+		"\t\t\t\"class\": \"return\",\n"+
+		"\t\t\t\"func\": \"G\",\n"+
+		"\t\t\t\"trueCount\": 1,\n"+
+		"\t\t\t\"falseCount\": 0\n"+
+		"\t\t}\n"+
+		"\t]\n"+
+		"}\n")
+}
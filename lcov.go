@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// writeLCOV writes the conditions in the LCOV tracefile format,
+// as described in the geninfo manual page.
+//
+// Each condition becomes a block with a branch for each outcome,
+// the first branch being true.
+// The report contains only branch records,
+// so that it can be merged with the line coverage from other tools.
+func writeLCOV(w io.Writer, conds []condition) error {
+	for _, file := range condsByFile(conds) {
+		if _, err := fmt.Fprintf(w, "TN:\nSF:%s\n", filepath.ToSlash(file.name)); err != nil {
+			return err
+		}
+
+		found, hit := 0, 0
+		for block, cond := range file.conds {
+			pos, _ := parsePosition(cond.Start)
+			counts := []int{cond.TrueCount, cond.FalseCount}
+			covered, outcomes := cond.outcomes()
+			for branch, count := range counts[:outcomes] {
+				// A dash means that the block was never executed.
+				taken := "-"
+				if cond.TrueCount > 0 || cond.FalseCount > 0 {
+					taken = fmt.Sprintf("%d", count)
+				}
+				if _, err := fmt.Fprintf(w, "BRDA:%d,%d,%d,%s\n",
+					pos.line, block, branch, taken); err != nil {
+					return err
+				}
+			}
+			found += outcomes
+			hit += covered
+		}
+
+		if _, err := fmt.Fprintf(w, "BRF:%d\nBRH:%d\nend_of_record\n", found, hit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
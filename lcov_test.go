@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_writeLCOV(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	conds := []condition{
+		{Start: "pkg/a.go:3:5", Code: "a", TrueCount: 2, FalseCount: 1},
+		{Start: "pkg/a.go:3:10", Code: "b", TrueCount: 2, FalseCount: 0},
+		{Start: "pkg/a.go:7:5", Code: "c", TrueCount: 0, FalseCount: 0},
+		{Start: "pkg/b.go:4:2", Code: "return 0", TrueCount: 1, FalseCount: 0, Kind: "return", Class: "return"},
+	}
+
+	var sb strings.Builder
+	err := writeLCOV(&sb, conds)
+
+	s.CheckEquals(err, nil)
+	s.CheckEquals(sb.String(), ""+
+		"TN:\n"+
+		"SF:pkg/a.go\n"+
+		"BRDA:3,0,0,2\n"+
+		"BRDA:3,0,1,1\n"+
+		"BRDA:3,1,0,2\n"+
+		"BRDA:3,1,1,0\n"+
+		"BRDA:7,2,0,-\n"+
+		"BRDA:7,2,1,-\n"+
+		"BRF:6\n"+
+		"BRH:3\n"+
+		"end_of_record\n"+
+		"TN:\n"+
+		"SF:pkg/b.go\n"+
+		"BRDA:4,0,0,1\n"+
+		"BRF:1\n"+
+		"BRH:1\n"+
+		"end_of_record\n")
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"bufio"
+	"encoding/binary"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/types"
 	"io"
 	"os"
@@ -23,27 +25,99 @@ func main() {
 func gobcoMain(stdout, stderr io.Writer, args ...string) int {
 	g := newGobco(stdout, stderr)
 	g.parseCommandLine(args)
+	switch g.command {
+	case "report":
+		g.report()
+		g.cleanUp()
+		return g.exitCode
+	case "minimize":
+		g.minimize()
+		g.cleanUp()
+		return g.exitCode
+	case "diff":
+		g.diff()
+		g.cleanUp()
+		return g.exitCode
+	}
 	g.prepareTmp()
-	if g.instrument() {
+	if !g.instrument() {
+		_, _ = io.WriteString(g.stdout, "nothing to instrument\n")
+	} else if g.command == "build" {
+		g.runGoBuild()
+	} else if g.command == "mutate" {
+		g.mutate()
+	} else {
 		g.runGoTest()
+		g.convertCounts()
 		g.printOutput()
-	} else {
-		_, _ = io.WriteString(g.stdout, "nothing to instrument\n")
 	}
 	g.cleanUp()
 	return g.exitCode
 }
 
 type gobco struct {
+	// Either "" for running the tests,
+	// "build" for building an instrumented program,
+	// "report" for reporting the coverage from existing stats files,
+	// "minimize" for selecting a minimal set of tests,
+	// "mutate" for checking whether the tests detect negated conditions,
+	// "show" for running the tests and showing the annotated source,
+	// or "diff" for comparing the coverage from two stats files.
+	command string
+
 	branch      bool
 	listAll     bool
 	immediately bool
 	keep        bool
 	coverTest   bool
+	exits       bool
+	recordTests bool
 
 	goTestArgs []string
+	classes    []string
 	args       []argInfo
 
+	// For the "build" command, the file to write the program to.
+	// Otherwise, the file to write the report from -format to,
+	// or "" for stdout.
+	output string
+
+	// The format of the machine-readable report, or "" for none.
+	format string
+
+	// Whether to print a table of the coverage per "file" or "func",
+	// sorted by "percent" or by the number of "uncovered" outcomes.
+	summary     string
+	summarySort string
+
+	// For the "report", "minimize" and "diff" commands,
+	// the stats files or directories.
+	reportArgs []string
+
+	// For the "mutate" command, the maximum number of conditions to negate,
+	// or 0 for all.
+	sample int
+
+	// The file to write the HTML report to, or "".
+	html string
+
+	// The stats file from a previous run,
+	// to show the changes in the Markdown report.
+	compare string
+
+	// The stats file whose covered outcomes must remain covered,
+	// and whether to rewrite it when the coverage improves.
+	baseline       string
+	updateBaseline bool
+
+	// The function whose annotated source is printed
+	// instead of the list of conditions, such as "Func" or "Type.Method".
+	show string
+
+	// For the "report" command, the location of the condition
+	// whose tests are listed, such as "file.go:12:5".
+	who string
+
 	statsFilename string
 
 	exitCode int
@@ -61,47 +135,123 @@ func newGobco(stdout io.Writer, stderr io.Writer) *gobco {
 
 func (g *gobco) parseCommandLine(argv []string) {
 	args := g.parseOptions(argv)
+	if g.command == "report" || g.command == "minimize" || g.command == "diff" {
+		g.reportArgs = args
+		return
+	}
 	g.parseArgs(args)
 }
 
 func (g *gobco) parseOptions(argv []string) []string {
 	var help, ver bool
 
-	flags := flag.NewFlagSet(filepath.Base(argv[0]), flag.ContinueOnError)
+	name := filepath.Base(argv[0])
+	usage := "[options] package..."
+	if len(argv) > 1 && isCommand(argv[1]) {
+		g.command = argv[1]
+		name += " " + argv[1]
+		argv = argv[1:]
+	}
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
 	flags.BoolVar(&help, "help", false,
 		"print the available command line options")
+	flags.StringVar(&g.baseline, "baseline", "",
+		"fail if an outcome that is covered in this stats `file` is no longer covered")
 	flags.BoolVar(&g.branch, "branch", false,
 		"cover branches, not conditions")
+	flags.Var(newSliceFlag(&g.classes), "class",
+		"only list the conditions of this `class`, such as error or nil")
+	flags.BoolVar(&g.exits, "exits", false,
+		"cover return statements and calls to panic as well")
+	flags.StringVar(&g.html, "html", "",
+		"write an HTML report with the annotated source to this `file`")
+	flags.StringVar(&g.format, "format", "",
+		"write the report in this `format`: cobertura, json, lcov, markdown or sarif")
 	flags.BoolVar(&g.immediately, "immediately", false,
 		"persist the coverage immediately at each check point")
 	flags.BoolVar(&g.keep, "keep", false,
 		"don't remove the temporary working directory")
 	flags.BoolVar(&g.listAll, "list-all", false,
 		"at finish, print also those conditions that are fully covered")
+	flags.BoolVar(&g.recordTests, "record-tests", false,
+		"record which tests cover each condition")
+	flags.StringVar(&g.show, "show", "",
+		"print the annotated source of this `function` instead of the conditions")
 	flags.StringVar(&g.statsFilename, "stats", "",
 		"load and persist the JSON coverage data to this `file`")
+	flags.StringVar(&g.summary, "summary", "",
+		"print the coverage per `unit`, either file or func")
+	flags.StringVar(&g.summarySort, "summary-sort", "percent",
+		"sort the -summary by `key`, either percent or uncovered")
 	flags.Var(newSliceFlag(&g.goTestArgs), "test",
 		"pass the `option` to \"go test\", such as -vet=off")
+	flags.BoolVar(&g.updateBaseline, "update-baseline", false,
+		"rewrite the -baseline file when the coverage improves")
 	flags.BoolVar(&g.verbose, "verbose", false,
 		"show progress messages")
+	flags.StringVar(&g.compare, "compare", "",
+		"show the changes since the previous run from this stats `file`")
 	flags.BoolVar(&g.coverTest, "cover-test", false,
 		"cover the test code as well")
 	flags.BoolVar(&ver, "version", false,
 		"print the gobco version")
+	switch g.command {
+	case "build":
+		usage = "[options] package -o file"
+		flags.StringVar(&g.output, "o", "",
+			"write the instrumented program to this `file`")
+	case "report":
+		usage = "[options] stats-file-or-directory..."
+		flags.StringVar(&g.who, "who", "",
+			"list the tests that cover the condition at this `location`")
+	case "minimize":
+		usage = "[options] stats-file-or-directory..."
+	case "show":
+		usage = "[options] package-or-file..."
+	case "diff":
+		usage = "[options] old-stats new-stats"
+	case "mutate":
+		flags.IntVar(&g.sample, "sample", 0,
+			"negate at most `n` of the covered conditions")
+	}
+	if g.command == "" || g.command == "report" || g.command == "show" {
+		flags.StringVar(&g.output, "o", "",
+			"write the report from -format to this `file` instead of stdout")
+	}
 
 	flags.SetOutput(g.stderr)
 	flags.Usage = func() {
 		_, _ = fmt.Fprintf(flags.Output(),
-			"usage: %s [options] package...\n", flags.Name())
+			"usage: %s %s\n", flags.Name(), usage)
 		flags.PrintDefaults()
 		g.exitCode = 2
 	}
 
-	err := flags.Parse(argv[1:])
-	if g.exitCode != 0 {
-		exit(g.exitCode)
+	// Like 'go build', allow options after the arguments,
+	// as in 'gobco build ./cmd/tool -o tool'.
+	// After '--', all remaining arguments are taken literally.
+	var args []string
+	rest := argv[1:]
+	for {
+		err := flags.Parse(rest)
+		if g.exitCode != 0 {
+			exit(g.exitCode)
+		}
+		g.check(err)
+
+		parsed := rest[:len(rest)-flags.NArg()]
+		rest = flags.Args()
+		if len(parsed) > 0 && parsed[len(parsed)-1] == "--" {
+			args = append(args, rest...)
+			break
+		}
+		if len(rest) == 0 {
+			break
+		}
+		args = append(args, rest[0])
+		rest = rest[1:]
 	}
-	g.check(err)
 
 	if help {
 		flags.SetOutput(g.stdout)
@@ -114,7 +264,64 @@ func (g *gobco) parseOptions(argv []string) []string {
 		exit(0)
 	}
 
-	return flags.Args()
+	if g.command == "build" && g.output == "" {
+		g.errf("%s: the -o option is required", flags.Name())
+		flags.Usage()
+		exit(g.exitCode)
+	}
+
+	if g.output != "" && g.format == "" && g.command != "build" {
+		g.errf("%s: the -o option requires -format", flags.Name())
+		flags.Usage()
+		exit(g.exitCode)
+	}
+
+	if g.compare != "" && g.format != "markdown" {
+		g.errf("%s: the -compare option requires -format markdown", flags.Name())
+		flags.Usage()
+		exit(g.exitCode)
+	}
+
+	if g.updateBaseline && g.baseline == "" {
+		g.errf("%s: the -update-baseline option requires -baseline", flags.Name())
+		flags.Usage()
+		exit(g.exitCode)
+	}
+
+	for _, class := range g.classes {
+		if !isClass(class) {
+			g.errf("%s: unknown class %q", flags.Name(), class)
+			flags.Usage()
+			exit(g.exitCode)
+		}
+	}
+
+	if g.summary != "" && g.summary != "file" && g.summary != "func" {
+		g.errf("%s: unknown summary %q", flags.Name(), g.summary)
+		flags.Usage()
+		exit(g.exitCode)
+	}
+	if g.summarySort != "percent" && g.summarySort != "uncovered" {
+		g.errf("%s: unknown summary sort key %q", flags.Name(), g.summarySort)
+		flags.Usage()
+		exit(g.exitCode)
+	}
+
+	if g.format != "" && !isReportFormat(g.format) {
+		g.errf("%s: unknown report format %q", flags.Name(), g.format)
+		flags.Usage()
+		exit(g.exitCode)
+	}
+
+	return args
+}
+
+func isCommand(arg string) bool {
+	switch arg {
+	case "build", "report", "minimize", "mutate", "show", "diff":
+		return true
+	}
+	return false
 }
 
 func (g *gobco) parseArgs(args []string) {
@@ -255,16 +462,24 @@ func (g *gobco) instrument() bool {
 		g.coverTest,
 		g.immediately,
 		g.listAll,
+		g.exits,
+		g.command == "build",
+		g.recordTests,
 		false,
 		nil,
 		map[*ast.Package]*types.Package{},
 		map[ast.Expr]types.Type{},
+		map[ast.Expr]constant.Value{},
+		types.Info{},
+		nil,
 		nil,
 		0,
 		map[ast.Expr]bool{},
 		map[ast.Expr]*exprSubst{},
 		map[ast.Stmt]*ast.Stmt{},
 		map[ast.Stmt]ast.Stmt{},
+		map[ast.Stmt][]*exprSubst{},
+		false,
 		false,
 		nil,
 	}
@@ -281,6 +496,15 @@ func (g *gobco) instrument() bool {
 }
 
 func (g *gobco) runGoTest() {
+	env := g.buildEnv
+	if g.format != "" && g.output == "" {
+		// The report from -format replaces the text output on stdout,
+		// so the output of the tests goes to stderr instead.
+		l := *env.logger
+		l.stdout = l.stderr
+		env.logger = &l
+	}
+
 	for _, arg := range g.args {
 		gopaths := ""
 		if !arg.module {
@@ -292,9 +516,117 @@ func (g *gobco) runGoTest() {
 			g.verbose,
 			gopaths,
 			g.statsFilename,
-			&g.buildEnv,
+			&env,
 		)
 	}
+
+	// The test processes have terminated,
+	// so the lock file that they share is no longer needed,
+	// see gobcoStats.lock in templates/gobco_unix.go.
+	_ = os.Remove(g.statsFilename + ".lock")
+}
+
+// runGoBuild builds the instrumented program.
+// When the program terminates,
+// it writes its counts to the directory from GOBCO_COVERDIR,
+// from where 'gobco report' reads them.
+func (g *gobco) runGoBuild() {
+	output, err := filepath.Abs(g.output)
+	g.check(err)
+
+	for _, arg := range g.args {
+		gopaths := ""
+		if !arg.module {
+			gopaths = g.gopaths()
+		}
+
+		args := []string{"go", "build", "-o", output, "."}
+		goBuild := exec.Command("go", args[1:]...)
+		goBuild.Stdout = g.stdout
+		goBuild.Stderr = g.stderr
+		goBuild.Dir = g.file(arg.instrDir)
+		goBuild.Env = goTest{}.env(g.tmpdir, gopaths, "")
+
+		cmdline := strings.Join(args, " ")
+		g.verbosef("Running %q in %q", cmdline, goBuild.Dir)
+
+		if err := goBuild.Run(); err != nil {
+			g.errf("go build %s: %s", arg.arg, err)
+			g.exitCode = 1
+		}
+	}
+}
+
+// convertCounts merges the counts files that are written in immediate mode
+// into the stats file.
+//
+// The counts files contain the counts that the test processes
+// have not merged into the stats file themselves,
+// for example because a process terminated abnormally.
+func (g *gobco) convertCounts() {
+	countsFilenames, err := filepath.Glob(g.statsFilename + ".*.counts")
+	g.check(err)
+	if len(countsFilenames) == 0 {
+		return
+	}
+
+	conds, err := g.load(g.statsFilename)
+	if err != nil {
+		g.errf("%s", err)
+		return
+	}
+
+	for _, countsFilename := range countsFilenames {
+		counts, err := os.ReadFile(countsFilename)
+		if err == nil {
+			conds, err = addCounts(conds, counts)
+		}
+		if err != nil {
+			g.errf("%s: %s", countsFilename, err)
+			return
+		}
+	}
+
+	if err := g.persist(g.statsFilename, conds); err != nil {
+		g.errf("%s", err)
+		return
+	}
+	for _, countsFilename := range countsFilenames {
+		g.check(os.Remove(countsFilename))
+	}
+}
+
+// addCounts adds the counters from the binary counts file
+// to the corresponding conditions,
+// see gobcoStats.countsFile in templates/gobco_fixed.go.
+func addCounts(conds []condition, counts []byte) ([]condition, error) {
+	if len(counts) < 16 || string(counts[:8]) != "gobcocnt" {
+		return nil, fmt.Errorf("invalid header")
+	}
+	n := binary.LittleEndian.Uint64(counts[8:])
+	if n%2 != 0 || uint64(len(counts)-16)/8 < n {
+		return nil, fmt.Errorf("invalid number of counters %d", n)
+	}
+
+	var keys [][2]string
+	if err := json.Unmarshal(counts[16+8*n:], &keys); err != nil {
+		return nil, fmt.Errorf("invalid conditions: %s", err)
+	}
+	if uint64(2*len(keys)) != n {
+		return nil, fmt.Errorf("must have exactly %d counters", 2*len(keys))
+	}
+
+	counter := func(i int) int {
+		return int(binary.LittleEndian.Uint64(counts[16+8*i:]))
+	}
+	countConds := make([]condition, len(keys))
+	for i, key := range keys {
+		countConds[i].Start = key[0]
+		countConds[i].Code = key[1]
+		countConds[i].TrueCount = counter(2 * i)
+		countConds[i].FalseCount = counter(2*i + 1)
+	}
+	return mergeConds(conds, countConds), nil
 }
 
 func (g *gobco) printOutput() {
@@ -304,16 +636,32 @@ func (g *gobco) printOutput() {
 	}
 	if err != nil {
 		g.logger.errf("%s", err)
+		g.exitCode = 1
+		return
 	}
 
-	cnt := 0
+	g.printReports(conds)
+}
+
+func (g *gobco) printConds(conds []condition) {
+	g.printTotals(conds)
+	g.printClasses(conds)
+	if g.summary != "" {
+		g.printSummary(conds)
+	}
+
+	for _, cond := range conds {
+		g.printCond(cond)
+	}
+}
+
+// printTotals prints the overall coverage of the conditions.
+func (g *gobco) printTotals(conds []condition) {
+	cnt, total := 0, 0
 	for _, c := range conds {
-		if c.TrueCount > 0 {
-			cnt++
-		}
-		if c.FalseCount > 0 {
-			cnt++
-		}
+		covered, outcomes := c.outcomes()
+		cnt += covered
+		total += outcomes
 	}
 
 	kind := "Condition coverage"
@@ -321,10 +669,44 @@ func (g *gobco) printOutput() {
 		kind = "Branch coverage"
 	}
 	g.outf("")
-	g.outf("%s: %d/%d", kind, cnt, len(conds)*2)
+	g.outf("%s: %d/%d", kind, cnt, total)
+}
 
-	for _, cond := range conds {
-		g.printCond(cond)
+// classes lists the classes of conditions, in the order of the output.
+var classes = []string{
+	"error", "nil", "len", "const", "call",
+	"comma-ok", "type", "enum", "other",
+	"return", "panic",
+}
+
+func isClass(name string) bool {
+	for _, class := range classes {
+		if class == name {
+			return true
+		}
+	}
+	return false
+}
+
+// printClasses prints the coverage for each class of conditions.
+func (g *gobco) printClasses(conds []condition) {
+	covered := map[string]int{}
+	total := map[string]int{}
+	for _, c := range conds {
+		cov, outcomes := c.outcomes()
+		covered[c.Class] += cov
+		total[c.Class] += outcomes
+	}
+
+	// A single class would only repeat the overall coverage.
+	if len(total) < 2 {
+		return
+	}
+
+	for _, class := range classes {
+		if total[class] > 0 {
+			g.outf("  %s: %d/%d", class, covered[class], total[class])
+		}
 	}
 }
 
@@ -354,12 +736,83 @@ func (g *gobco) load(filename string) ([]condition, error) {
 	var data []condition
 	decoder := json.NewDecoder(bufio.NewReader(file))
 	decoder.DisallowUnknownFields()
-	g.check(decoder.Decode(&data))
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf(
+			"the stats file %s is corrupt or incomplete: %s",
+			filename, err)
+	}
 
 	return data, nil
 }
 
+// persist writes the conditions to the stats file,
+// in the same format as gobcoStats.persist in templates/gobco_fixed.go.
+//
+// The file is replaced atomically,
+// so that it is never left in a partial state.
+func (g *gobco) persist(filename string, conds []condition) (err error) {
+	file, err := createTemp(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+		}
+	}()
+
+	buf := bufio.NewWriter(file)
+	encoder := json.NewEncoder(buf)
+	encoder.SetIndent("", "\t")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(conds); err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filename)
+}
+
 func (g *gobco) printCond(cond condition) {
+	for _, msg := range g.describeCond(cond) {
+		g.outf("%s", msg)
+	}
+}
+
+// messages collects the lines of the text output.
+type messages []string
+
+func (m *messages) addf(format string, args ...interface{}) {
+	*m = append(*m, fmt.Sprintf(format, args...))
+}
+
+// describeCond returns the lines that printCond prints for the condition.
+// Without -list-all, the lines only mention the outcomes
+// that were not covered.
+func (g *gobco) describeCond(cond condition) []string {
+	var msgs messages
+	g.describeCondTo(&msgs, cond)
+	return msgs
+}
+
+func (g *gobco) describeCondTo(msgs *messages, cond condition) {
+	if len(g.classes) > 0 && !g.hasClass(cond.Class) {
+		return
+	}
+	switch cond.Kind {
+	case "enum":
+		g.describeEnumValue(msgs, cond)
+		return
+	case "return", "panic":
+		g.describeExit(msgs, cond)
+		return
+	}
+	defer g.describeForced(msgs, cond)
 
 	trueCount := cond.TrueCount
 	falseCount := cond.FalseCount
@@ -371,35 +824,117 @@ func (g *gobco) printCond(cond condition) {
 	code := cond.Code
 	switch {
 	case trueCount == 0 && falseCount == 0:
-		g.outf("%s: condition %q was never evaluated",
+		msgs.addf("%s: condition %q was never evaluated",
 			start, code)
 	case trueCount == 0 && falseCount == 1:
-		g.outf("%s: condition %q was once false but never true",
+		msgs.addf("%s: condition %q was once false but never true",
 			start, code)
 	case trueCount == 0:
-		g.outf("%s: condition %q was %d times false but never true",
+		msgs.addf("%s: condition %q was %d times false but never true",
 			start, code, falseCount)
 	case trueCount == 1 && falseCount == 0:
-		g.outf("%s: condition %q was once true but never false",
+		msgs.addf("%s: condition %q was once true but never false",
 			start, code)
 	case trueCount == 1 && falseCount == 1:
-		g.outf("%s: condition %q was once true and once false",
+		msgs.addf("%s: condition %q was once true and once false",
 			start, code)
 	case trueCount == 1:
-		g.outf("%s: condition %q was once true and %d times false",
+		msgs.addf("%s: condition %q was once true and %d times false",
 			start, code, falseCount)
 	case falseCount == 0:
-		g.outf("%s: condition %q was %d times true but never false",
+		msgs.addf("%s: condition %q was %d times true but never false",
 			start, code, trueCount)
 	case falseCount == 1:
-		g.outf("%s: condition %q was %d times true and once false",
+		msgs.addf("%s: condition %q was %d times true and once false",
 			start, code, trueCount)
 	default:
-		g.outf("%s: condition %q was %d times true and %d times false",
+		msgs.addf("%s: condition %q was %d times true and %d times false",
 			start, code, trueCount, falseCount)
 	}
 }
 
+// describeForced describes how often the outcome of the condition was forced
+// using GOBCO_FORCE.
+func (g *gobco) describeForced(msgs *messages, cond condition) {
+	printCount := func(n int, value string) {
+		switch {
+		case n == 1:
+			msgs.addf("%s: condition %q was forced once to %s",
+				cond.Start, cond.Code, value)
+		case n > 1:
+			msgs.addf("%s: condition %q was forced %d times to %s",
+				cond.Start, cond.Code, n, value)
+		}
+	}
+	printCount(cond.ForcedTrueCount, "true")
+	printCount(cond.ForcedFalseCount, "false")
+}
+
+func (g *gobco) hasClass(class string) bool {
+	for _, c := range g.classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// describeEnumValue describes whether a value of an enum type has been observed
+// at the tag of a switch statement.
+func (g *gobco) describeEnumValue(msgs *messages, cond condition) {
+	subject, value := cond.enumValue()
+
+	switch {
+	case cond.TrueCount == 0:
+		msgs.addf("%s: %s never saw %s",
+			cond.Start, subject, value)
+	case !g.listAll:
+		break
+	case cond.TrueCount == 1:
+		msgs.addf("%s: %s saw %s once",
+			cond.Start, subject, value)
+	default:
+		msgs.addf("%s: %s saw %s %d times",
+			cond.Start, subject, value, cond.TrueCount)
+	}
+}
+
+// enumValue splits the code of an enum condition, such as "kind == KindDir",
+// into the switch statement, such as `switch on "kind"`,
+// and the value of the enum type, such as "Kind value KindDir".
+//
+// Stats files from older versions of gobco don't record the type.
+// Stats files that have been edited by hand may contain arbitrary code.
+func (c condition) enumValue() (subject, value string) {
+	subject, value = "switch", c.Code
+	if sep := strings.LastIndex(c.Code, " == "); sep != -1 {
+		subject = fmt.Sprintf("switch on %q", c.Code[:sep])
+		value = c.Code[sep+len(" == "):]
+	}
+	if c.Type == "" {
+		return subject, "value " + value
+	}
+	return subject, c.Type + " value " + value
+}
+
+// describeExit describes how often a return statement or a call to panic
+// has been executed.
+func (g *gobco) describeExit(msgs *messages, cond condition) {
+	switch {
+	case cond.TrueCount == 0:
+		msgs.addf("%s at %s was never executed",
+			cond.Kind, cond.Start)
+	case !g.listAll:
+		break
+	case cond.TrueCount == 1:
+		msgs.addf("%s at %s was executed once",
+			cond.Kind, cond.Start)
+	default:
+		msgs.addf("%s at %s was executed %d times",
+			cond.Kind, cond.Start, cond.TrueCount)
+	}
+}
+
 // goTest groups the functions that run 'go test' with the proper arguments.
 type goTest struct{}
 
@@ -473,7 +1008,9 @@ func (goTest) env(tmpdir, gopaths, statsFilename string) []string {
 		env = append(env, "GO111MODULE=off")
 	}
 
-	env = append(env, "GOBCO_STATS="+statsFilename)
+	if statsFilename != "" {
+		env = append(env, "GOBCO_STATS="+statsFilename)
+	}
 
 	return env
 }
@@ -582,4 +1119,85 @@ type condition struct {
 	Code       string
 	TrueCount  int
 	FalseCount int
+
+	// Either "" for a condition,
+	// "enum" for a value of an enum type in a switch statement,
+	// "return" for a return statement,
+	// or "panic" for a call to the built-in panic function.
+	// All kinds except conditions have only a single outcome.
+	Kind string `json:",omitempty"`
+
+	// The class of the condition, such as "error" for 'err != nil'
+	// or "const" for 'x == 5', see instrumenter.classify.
+	Class string `json:",omitempty"`
+
+	// With -record-tests, the names of the tests
+	// that covered the condition, sorted by name.
+	TrueTests  []string `json:",omitempty"`
+	FalseTests []string `json:",omitempty"`
+
+	// The evaluations whose outcome was forced using GOBCO_FORCE.
+	// They are not included in TrueCount and FalseCount.
+	ForcedTrueCount  int `json:",omitempty"`
+	ForcedFalseCount int `json:",omitempty"`
+
+	// The position after the condition, such as "file.go:12:15",
+	// or "" if unknown.
+	End string `json:",omitempty"`
+
+	// The function that contains the condition,
+	// such as "Func" or "Type.Method", or "" outside of functions.
+	Func string `json:",omitempty"`
+
+	// For enum values, the type of the switch tag,
+	// such as "Kind" or "fs.FileMode".
+	Type string `json:",omitempty"`
+}
+
+// outcomes returns how many of the possible outcomes of the condition
+// have been covered.
+func (c condition) outcomes() (covered, total int) {
+	if c.TrueCount > 0 {
+		covered++
+	}
+	if c.Kind != "" {
+		return covered, 1
+	}
+	if c.FalseCount > 0 {
+		covered++
+	}
+	return covered, 2
+}
+
+// state classifies the coverage of the condition as "never",
+// "true-only", "false-only" or "covered".
+func (c condition) state() string {
+	switch {
+	case c.TrueCount > 0 && (c.FalseCount > 0 || c.Kind != ""):
+		return "covered"
+	case c.TrueCount > 0:
+		return "true-only"
+	case c.FalseCount > 0 && c.Kind == "":
+		return "false-only"
+	default:
+		return "never"
+	}
+}
+
+// summary describes the condition and its counts in a single line,
+// such as "i > 0: 3 times true, once false".
+func (c condition) summary() string {
+	if c.Kind != "" {
+		return fmt.Sprintf("%s %s: %s", c.Kind, c.Code, times(c.TrueCount))
+	}
+	return fmt.Sprintf("%s: %s true, %s false",
+		c.Code, times(c.TrueCount), times(c.FalseCount))
+}
+
+// times describes a count, such as "once" or "3 times".
+func times(n int) string {
+	if n == 1 {
+		return "once"
+	}
+	return fmt.Sprintf("%d times", n)
 }
@@ -2,9 +2,14 @@ package main
 
 import (
 	"bytes"
+	"fmt"
 	"log"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -183,6 +188,112 @@ func Test_gobco_parseCommandLine__two_packages(t *testing.T) {
 		"checking multiple packages doesn't work yet")
 }
 
+func Test_gobco_parseCommandLine__build(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+
+	g.parseCommandLine([]string{"gobco", "build", "./testdata/program", "-o", "program"})
+
+	s.CheckEquals(g.exitCode, 0)
+	s.CheckEquals(g.command, "build")
+	s.CheckEquals(g.output, "program")
+	s.CheckEquals(len(g.args), 1)
+	s.CheckEquals(g.args[0].arg, filepath.FromSlash("./testdata/program"))
+}
+
+func Test_gobco_parseCommandLine__build_without_output(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+
+	s.CheckPanics(
+		func() { g.parseCommandLine([]string{"gobco", "build", "./testdata/program"}) },
+		exited(2))
+
+	s.CheckContains(s.Stderr(), ""+
+		"gobco build: the -o option is required\n"+
+		"usage: gobco build [options] package -o file\n")
+}
+
+func Test_gobco_parseCommandLine__output_without_format(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+
+	s.CheckPanics(
+		func() { g.parseCommandLine([]string{"gobco", "-o", "coverage.xml", "."}) },
+		exited(2))
+
+	s.CheckContains(s.Stderr(), ""+
+		"gobco: the -o option requires -format\n"+
+		"usage: gobco [options] package...\n")
+}
+
+func Test_gobco_parseCommandLine__compare_without_markdown(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+
+	s.CheckPanics(
+		func() {
+			g.parseCommandLine([]string{"gobco", "report",
+				"-format", "json", "-compare", "old.json", "new.json"})
+		},
+		exited(2))
+
+	s.CheckContains(s.Stderr(), ""+
+		"gobco report: the -compare option requires -format markdown\n"+
+		"usage: gobco report [options] stats-file-or-directory...\n")
+}
+
+func Test_gobco_parseOptions__double_dash(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+
+	args := g.parseOptions([]string{"gobco", "-keep", "--", "pkg", "-list-all"})
+
+	s.CheckEquals(args, []string{"pkg", "-list-all"})
+	s.CheckEquals(g.keep, true)
+	s.CheckEquals(g.listAll, false)
+}
+
+func Test_gobco_parseCommandLine__unknown_format(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+
+	s.CheckPanics(
+		func() { g.parseCommandLine([]string{"gobco", "-format", "unknown", "."}) },
+		exited(2))
+
+	s.CheckContains(s.Stderr(), ""+
+		"gobco: unknown report format \"unknown\"\n"+
+		"usage: gobco [options] package...\n")
+}
+
+func Test_gobco_parseCommandLine__unknown_class(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+
+	s.CheckPanics(
+		func() { g.parseCommandLine([]string{"gobco", "-class", "error", "-class", "eror", "."}) },
+		exited(2))
+
+	s.CheckContains(s.Stderr(), ""+
+		"gobco: unknown class \"eror\"\n"+
+		"usage: gobco [options] package...\n")
+}
+
 func Test_gobco_parseCommandLine__usage(t *testing.T) {
 	s := NewSuite(t)
 	defer s.TearDownTest()
@@ -197,22 +308,46 @@ func Test_gobco_parseCommandLine__usage(t *testing.T) {
 	s.CheckEquals(s.Stderr(), ""+
 		"flag provided but not defined: -invalid\n"+
 		"usage: gobco [options] package...\n"+
+		"  -baseline file\n"+
+		"    \tfail if an outcome that is covered in this stats file is no longer covered\n"+
 		"  -branch\n"+
 		"    \tcover branches, not conditions\n"+
+		"  -class class\n"+
+		"    \tonly list the conditions of this class, such as error or nil\n"+
+		"  -compare file\n"+
+		"    \tshow the changes since the previous run from this stats file\n"+
 		"  -cover-test\n"+
 		"    \tcover the test code as well\n"+
+		"  -exits\n"+
+		"    \tcover return statements and calls to panic as well\n"+
+		"  -format format\n"+
+		"    \twrite the report in this format: cobertura, json, lcov, markdown or sarif\n"+
 		"  -help\n"+
 		"    \tprint the available command line options\n"+
+		"  -html file\n"+
+		"    \twrite an HTML report with the annotated source to this file\n"+
 		"  -immediately\n"+
 		"    \tpersist the coverage immediately at each check point\n"+
 		"  -keep\n"+
 		"    \tdon't remove the temporary working directory\n"+
 		"  -list-all\n"+
 		"    \tat finish, print also those conditions that are fully covered\n"+
+		"  -o file\n"+
+		"    \twrite the report from -format to this file instead of stdout\n"+
+		"  -record-tests\n"+
+		"    \trecord which tests cover each condition\n"+
+		"  -show function\n"+
+		"    \tprint the annotated source of this function instead of the conditions\n"+
 		"  -stats file\n"+
 		"    \tload and persist the JSON coverage data to this file\n"+
+		"  -summary unit\n"+
+		"    \tprint the coverage per unit, either file or func\n"+
+		"  -summary-sort key\n"+
+		"    \tsort the -summary by key, either percent or uncovered (default \"percent\")\n"+
 		"  -test option\n"+
 		"    \tpass the option to \"go test\", such as -vet=off\n"+
+		"  -update-baseline\n"+
+		"    \trewrite the -baseline file when the coverage improves\n"+
 		"  -verbose\n"+
 		"    \tshow progress messages\n"+
 		"  -version\n"+
@@ -233,22 +368,46 @@ func Test_gobco_parseCommandLine__help(t *testing.T) {
 
 	s.CheckEquals(stdout.String(), ""+
 		"usage: gobco [options] package...\n"+
+		"  -baseline file\n"+
+		"    \tfail if an outcome that is covered in this stats file is no longer covered\n"+
 		"  -branch\n"+
 		"    \tcover branches, not conditions\n"+
+		"  -class class\n"+
+		"    \tonly list the conditions of this class, such as error or nil\n"+
+		"  -compare file\n"+
+		"    \tshow the changes since the previous run from this stats file\n"+
 		"  -cover-test\n"+
 		"    \tcover the test code as well\n"+
+		"  -exits\n"+
+		"    \tcover return statements and calls to panic as well\n"+
+		"  -format format\n"+
+		"    \twrite the report in this format: cobertura, json, lcov, markdown or sarif\n"+
 		"  -help\n"+
 		"    \tprint the available command line options\n"+
+		"  -html file\n"+
+		"    \twrite an HTML report with the annotated source to this file\n"+
 		"  -immediately\n"+
 		"    \tpersist the coverage immediately at each check point\n"+
 		"  -keep\n"+
 		"    \tdon't remove the temporary working directory\n"+
 		"  -list-all\n"+
 		"    \tat finish, print also those conditions that are fully covered\n"+
+		"  -o file\n"+
+		"    \twrite the report from -format to this file instead of stdout\n"+
+		"  -record-tests\n"+
+		"    \trecord which tests cover each condition\n"+
+		"  -show function\n"+
+		"    \tprint the annotated source of this function instead of the conditions\n"+
 		"  -stats file\n"+
 		"    \tload and persist the JSON coverage data to this file\n"+
+		"  -summary unit\n"+
+		"    \tprint the coverage per unit, either file or func\n"+
+		"  -summary-sort key\n"+
+		"    \tsort the -summary by key, either percent or uncovered (default \"percent\")\n"+
 		"  -test option\n"+
 		"    \tpass the option to \"go test\", such as -vet=off\n"+
+		"  -update-baseline\n"+
+		"    \trewrite the -baseline file when the coverage improves\n"+
 		"  -verbose\n"+
 		"    \tshow progress messages\n"+
 		"  -version\n"+
@@ -299,6 +458,7 @@ func Test_gobco_instrument(t *testing.T) {
 		"fail_test.go",
 		"gobco_fixed.go",
 		"gobco_no_testmain_test.go",
+		"gobco_os.go",
 		"gobco_variable.go",
 		"random.go"})
 
@@ -330,15 +490,15 @@ func Test_gobco_printCond(t *testing.T) {
 
 	g := s.newGobco()
 
-	g.printCond(condition{"location", "zero-zero", 0, 0})
-	g.printCond(condition{"location", "zero-once", 0, 1})
-	g.printCond(condition{"location", "zero-many", 0, 5})
-	g.printCond(condition{"location", "once-zero", 1, 0})
-	g.printCond(condition{"location", "once-once", 1, 1})
-	g.printCond(condition{"location", "once-many", 1, 5})
-	g.printCond(condition{"location", "many-zero", 5, 0})
-	g.printCond(condition{"location", "many-once", 5, 1})
-	g.printCond(condition{"location", "many-many", 5, 5})
+	g.printCond(condition{Start: "location", Code: "zero-zero", TrueCount: 0, FalseCount: 0})
+	g.printCond(condition{Start: "location", Code: "zero-once", TrueCount: 0, FalseCount: 1})
+	g.printCond(condition{Start: "location", Code: "zero-many", TrueCount: 0, FalseCount: 5})
+	g.printCond(condition{Start: "location", Code: "once-zero", TrueCount: 1, FalseCount: 0})
+	g.printCond(condition{Start: "location", Code: "once-once", TrueCount: 1, FalseCount: 1})
+	g.printCond(condition{Start: "location", Code: "once-many", TrueCount: 1, FalseCount: 5})
+	g.printCond(condition{Start: "location", Code: "many-zero", TrueCount: 5, FalseCount: 0})
+	g.printCond(condition{Start: "location", Code: "many-once", TrueCount: 5, FalseCount: 1})
+	g.printCond(condition{Start: "location", Code: "many-many", TrueCount: 5, FalseCount: 5})
 
 	expectedOut := "" +
 		"location: condition \"zero-zero\" was never evaluated\n" +
@@ -356,15 +516,15 @@ func Test_gobco_printCond__listAll(t *testing.T) {
 	g := s.newGobco()
 
 	g.listAll = true
-	g.printCond(condition{"location", "zero-zero", 0, 0})
-	g.printCond(condition{"location", "zero-once", 0, 1})
-	g.printCond(condition{"location", "zero-many", 0, 5})
-	g.printCond(condition{"location", "once-zero", 1, 0})
-	g.printCond(condition{"location", "once-once", 1, 1})
-	g.printCond(condition{"location", "once-many", 1, 5})
-	g.printCond(condition{"location", "many-zero", 5, 0})
-	g.printCond(condition{"location", "many-once", 5, 1})
-	g.printCond(condition{"location", "many-many", 5, 5})
+	g.printCond(condition{Start: "location", Code: "zero-zero", TrueCount: 0, FalseCount: 0})
+	g.printCond(condition{Start: "location", Code: "zero-once", TrueCount: 0, FalseCount: 1})
+	g.printCond(condition{Start: "location", Code: "zero-many", TrueCount: 0, FalseCount: 5})
+	g.printCond(condition{Start: "location", Code: "once-zero", TrueCount: 1, FalseCount: 0})
+	g.printCond(condition{Start: "location", Code: "once-once", TrueCount: 1, FalseCount: 1})
+	g.printCond(condition{Start: "location", Code: "once-many", TrueCount: 1, FalseCount: 5})
+	g.printCond(condition{Start: "location", Code: "many-zero", TrueCount: 5, FalseCount: 0})
+	g.printCond(condition{Start: "location", Code: "many-once", TrueCount: 5, FalseCount: 1})
+	g.printCond(condition{Start: "location", Code: "many-many", TrueCount: 5, FalseCount: 5})
 
 	expectedOut := "" +
 		"location: condition \"zero-zero\" was never evaluated\n" +
@@ -379,6 +539,70 @@ func Test_gobco_printCond__listAll(t *testing.T) {
 	s.CheckEquals(s.Stdout(), expectedOut)
 }
 
+func Test_gobco_printCond__enum(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+
+	g.printCond(condition{Start: "location", Code: "kind == KindFile", TrueCount: 0, FalseCount: 0, Kind: "enum", Type: "Kind"})
+	g.printCond(condition{Start: "location", Code: "kind == KindDir", TrueCount: 0, FalseCount: 3, Kind: "enum", Type: "Kind"})
+	g.printCond(condition{Start: "location", Code: "(a + b) == KindArchive", TrueCount: 1, FalseCount: 0, Kind: "enum", Type: "Kind"})
+	// Stats files from older versions don't record the type.
+	g.printCond(condition{Start: "location", Code: "mode == fs.ModeDir", TrueCount: 0, FalseCount: 0, Kind: "enum"})
+	// Stats files that have been edited by hand may contain any code.
+	g.printCond(condition{Start: "location", Code: "KindLink", TrueCount: 0, FalseCount: 0, Kind: "enum", Type: "Kind"})
+	g.listAll = true
+	g.printCond(condition{Start: "location", Code: "kind == KindDir", TrueCount: 1, FalseCount: 3, Kind: "enum", Type: "Kind"})
+	g.printCond(condition{Start: "location", Code: "kind == KindDir", TrueCount: 5, FalseCount: 3, Kind: "enum", Type: "Kind"})
+
+	expectedOut := "" +
+		"location: switch on \"kind\" never saw Kind value KindFile\n" +
+		"location: switch on \"kind\" never saw Kind value KindDir\n" +
+		"location: switch on \"mode\" never saw value fs.ModeDir\n" +
+		"location: switch never saw Kind value KindLink\n" +
+		"location: switch on \"kind\" saw Kind value KindDir once\n" +
+		"location: switch on \"kind\" saw Kind value KindDir 5 times\n"
+	s.CheckEquals(s.Stdout(), expectedOut)
+}
+
+func Test_gobco_printCond__exits(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+
+	g.printCond(condition{Start: "location", Code: "return", TrueCount: 0, FalseCount: 0, Kind: "return", Class: "return"})
+	g.printCond(condition{Start: "location", Code: "panic", TrueCount: 0, FalseCount: 0, Kind: "panic", Class: "panic"})
+	g.printCond(condition{Start: "location", Code: "return", TrueCount: 3, FalseCount: 0, Kind: "return", Class: "return"})
+	g.listAll = true
+	g.printCond(condition{Start: "location", Code: "return", TrueCount: 1, FalseCount: 0, Kind: "return", Class: "return"})
+	g.printCond(condition{Start: "location", Code: "panic", TrueCount: 3, FalseCount: 0, Kind: "panic", Class: "panic"})
+
+	expectedOut := "" +
+		"return at location was never executed\n" +
+		"panic at location was never executed\n" +
+		"return at location was executed once\n" +
+		"panic at location was executed 3 times\n"
+	s.CheckEquals(s.Stdout(), expectedOut)
+}
+
+func Test_gobco_printCond__forced(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+
+	g.printCond(condition{Start: "location", Code: "err != nil", TrueCount: 0, FalseCount: 0, ForcedTrueCount: 1})
+	g.printCond(condition{Start: "location", Code: "ok", TrueCount: 2, FalseCount: 3, ForcedFalseCount: 4})
+
+	expectedOut := "" +
+		"location: condition \"err != nil\" was never evaluated\n" +
+		"location: condition \"err != nil\" was forced once to true\n" +
+		"location: condition \"ok\" was forced 4 times to false\n"
+	s.CheckEquals(s.Stdout(), expectedOut)
+}
+
 func Test_gobco_cleanup(t *testing.T) {
 	s := NewSuite(t)
 	defer s.TearDownTest()
@@ -395,6 +619,7 @@ func Test_gobco_cleanup(t *testing.T) {
 		"fail_test.go",
 		"gobco_fixed.go",
 		"gobco_no_testmain_test.go",
+		"gobco_os.go",
 		"gobco_variable.go",
 		"random.go"})
 
@@ -475,6 +700,48 @@ func Test_gobcoMain__TestMain(t *testing.T) {
 	_ = stderr
 }
 
+func Test_gobcoMain__TestMain_return(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco", "-list-all", "./testdata/testmainreturn")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+		"testdata/testmainreturn/small.go:4:9: " +
+			"condition \"i < 10\" was once true and once false",
+	})
+	s.CheckEquals(stderr, "")
+}
+
+func Test_gobcoMain__TestMain_helper(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco", "-list-all", "./testdata/testmainhelper")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+		"testdata/testmainhelper/small.go:4:9: " +
+			"condition \"i < 10\" was once true and once false",
+	})
+	s.CheckEquals(stderr, "")
+}
+
+func Test_gobcoMain__TestMain_verify(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco", "-list-all", "./testdata/testmainverify")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+		"testdata/testmainverify/small.go:4:9: " +
+			"condition \"i < 10\" was once true and once false",
+	})
+	s.CheckEquals(stderr, "")
+}
+
 func Test_gobcoMain__TestMainTest(t *testing.T) {
 	s := NewSuite(t)
 	defer s.TearDownTest()
@@ -506,6 +773,8 @@ func Test_gobcoMain__blackBox(t *testing.T) {
 
 	s.CheckEquals(s.GobcoLines(stdout), []string{
 		"Condition coverage: 4/8",
+		"  const: 2/4",
+		"  other: 2/4",
 		"testdata/pkgname/main.go:4:5: " +
 			"condition \"cond\" was once true but never false",
 		"testdata/pkgname/main.go:11:5: " +
@@ -552,6 +821,8 @@ func Test_gobcoMain__branch(t *testing.T) {
 
 	s.CheckEquals(s.GobcoLines(stdout), []string{
 		"Branch coverage: 0/10",
+		"  const: 0/8",
+		"  other: 0/2",
 		"testdata/branch/branch.go:6:5: " +
 			"condition \"x > 0 && x > 100\" was never evaluated",
 		"testdata/branch/branch.go:10:7: " +
@@ -579,3 +850,1117 @@ func Test_gobcoMain__issue38(t *testing.T) {
 	})
 	s.CheckEquals(stderr, "")
 }
+
+func Test_gobcoMain__enum(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco", "./testdata/enum")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 3/4",
+		"  const: 2/2",
+		"  enum: 1/2",
+		"testdata/enum/enum.go:12:9: " +
+			"switch on \"kind\" never saw Kind value KindArchive",
+	})
+	s.CheckEquals(stderr, "")
+}
+
+func Test_gobcoMain__class(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco",
+		"-branch", "-class", "other", "./testdata/branch")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Branch coverage: 0/10",
+		"  const: 0/8",
+		"  other: 0/2",
+		"testdata/branch/branch.go:6:5: " +
+			"condition \"x > 0 && x > 100\" was never evaluated",
+	})
+	s.CheckEquals(stderr, "")
+}
+
+func Test_gobcoMain__exits(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco", "-exits", "./testdata/exits")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 3/7",
+		"  const: 2/4",
+		"  return: 1/2",
+		"  panic: 0/1",
+		"testdata/exits/exits.go:5:7: " +
+			"condition \"x < 0\" was once false but never true",
+		"return at testdata/exits/exits.go:6:3 was never executed",
+		"testdata/exits/exits.go:7:7: " +
+			"condition \"x == 0\" was once false but never true",
+		"panic at testdata/exits/exits.go:8:3 was never executed",
+	})
+	s.CheckEquals(stderr, "")
+}
+
+func Test_gobcoMain__concurrent(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco", "-list-all", "./testdata/concurrent")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+		"testdata/concurrent/even.go:4:9: " +
+			"condition \"i%2 == 0\" was 50000 times true and 50000 times false",
+	})
+	s.CheckEquals(stderr, "")
+}
+
+// The instrumented code has no data races,
+// not even when the counts are persisted immediately
+// or attributed to the tests.
+func Test_gobcoMain__concurrent_race(t *testing.T) {
+	if !raceSupported() {
+		t.Skip("the race detector is not available")
+	}
+
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco",
+		"-immediately", "-record-tests", "-list-all",
+		"-test", "-race", "./testdata/concurrent")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+		"testdata/concurrent/even.go:4:9: " +
+			"condition \"i%2 == 0\" was 50000 times true and 50000 times false",
+	})
+	s.CheckEquals(stderr, "")
+}
+
+// raceSupported returns whether 'go test -race' works on this platform,
+// which requires cgo.
+func raceSupported() bool {
+	out, err := exec.Command("go", "env", "CGO_ENABLED").Output()
+	if err != nil || strings.TrimSpace(string(out)) != "1" {
+		return false
+	}
+	switch runtime.GOOS + "/" + runtime.GOARCH {
+	case "linux/amd64", "linux/arm64", "linux/ppc64le", "linux/s390x",
+		"darwin/amd64", "darwin/arm64",
+		"freebsd/amd64", "netbsd/amd64", "windows/amd64":
+		return true
+	}
+	return false
+}
+
+func Test_gobcoMain__immediately(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco",
+		"-immediately", "-list-all", "./testdata/concurrent")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+		"testdata/concurrent/even.go:4:9: " +
+			"condition \"i%2 == 0\" was 50000 times true and 50000 times false",
+	})
+	s.CheckEquals(stderr, "")
+}
+
+// Test binaries from different packages that share a stats file
+// keep each other's conditions, even in immediate mode.
+func Test_gobcoMain__shared_stats(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	statsFilename := filepath.Join(t.TempDir(), "stats.json")
+
+	stdout, stderr := s.RunMain(0, "gobco",
+		"-stats", statsFilename, "./testdata/issue38")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 1/2",
+		"testdata/issue38/main.go:4:5: " +
+			"condition \"a >= 0\" was 2 times true but never false",
+	})
+	s.CheckEquals(stderr, "")
+
+	stdout, stderr = s.RunMain(0, "gobco",
+		"-immediately", "-list-all",
+		"-stats", statsFilename, "./testdata/concurrent")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 3/4",
+		"testdata/concurrent/even.go:4:9: " +
+			"condition \"i%2 == 0\" was 50000 times true and 50000 times false",
+		"testdata/issue38/main.go:4:5: " +
+			"condition \"a >= 0\" was 2 times true but never false",
+	})
+	s.CheckEquals(stderr, "")
+
+	_, err := os.Stat(statsFilename + ".lock")
+	s.CheckEquals(os.IsNotExist(err), true)
+}
+
+// In immediate mode, the counts survive even if the test process terminates
+// abnormally.
+func Test_gobcoMain__immediately_crash(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(1, "gobco",
+		"-immediately", "-list-all", "./testdata/crash")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+		"testdata/crash/crash.go:4:9: " +
+			"condition \"i < 10\" was 10 times true and 5 times false",
+	})
+	s.CheckContains(stderr, "exit status 1")
+}
+
+// A test that runs its own binary as a helper process
+// gets the counts from both processes.
+func Test_gobcoMain__subprocess(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco",
+		"-list-all", "./testdata/subprocess")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+		"testdata/subprocess/small.go:4:9: " +
+			"condition \"i < 10\" was 2 times true and once false",
+	})
+	s.CheckEquals(stderr, "")
+}
+
+// In immediate mode, each process writes its own counts file.
+func Test_gobcoMain__subprocess_immediately(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco",
+		"-immediately", "-list-all", "./testdata/subprocess")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+		"testdata/subprocess/small.go:4:9: " +
+			"condition \"i < 10\" was 2 times true and once false",
+	})
+	s.CheckEquals(stderr, "")
+}
+
+// A program from 'gobco build' writes its counts to GOBCO_COVERDIR,
+// from where 'gobco report' reads them.
+func Test_gobcoMain__build(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+	program := g.file("program")
+	coverDir := g.file("coverdir")
+	s.CheckEquals(os.Mkdir(coverDir, 0o777), nil)
+
+	stdout, stderr := s.RunMain(0, "gobco",
+		"build", "./testdata/program", "-o", program)
+	s.CheckEquals(stdout, "")
+	s.CheckEquals(stderr, "")
+
+	for _, args := range [][]string{nil, {"world"}, {"gopher"}} {
+		cmd := exec.Command(program, args...)
+		cmd.Env = append(os.Environ(), "GOBCO_COVERDIR="+coverDir)
+		out, err := cmd.CombinedOutput()
+		s.CheckEquals(err, nil)
+		s.CheckContains(string(out), "hello")
+	}
+
+	stdout, stderr = s.RunMain(0, "gobco",
+		"report", "-list-all", coverDir)
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+		"testdata/program/main.go:9:5: " +
+			"condition \"len(os.Args) > 1\" was 2 times true and once false",
+	})
+	s.CheckEquals(stderr, "")
+}
+
+// Without GOBCO_COVERDIR, the program works as usual
+// but doesn't write any coverage data.
+func Test_gobcoMain__build_without_coverdir(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+	program := g.file("program")
+
+	stdout, stderr := s.RunMain(0, "gobco",
+		"build", "-o", program, "./testdata/program")
+	s.CheckEquals(stdout, "")
+	s.CheckEquals(stderr, "")
+
+	var out, errOut bytes.Buffer
+	cmd := exec.Command(program)
+	cmd.Env = append(os.Environ(), "GOBCO_COVERDIR=", "GOBCO_STATS=")
+	cmd.Stdout = &out
+	cmd.Stderr = &errOut
+	s.CheckEquals(cmd.Run(), nil)
+	s.CheckEquals(out.String(), "hello\n")
+	s.CheckEquals(errOut.String(),
+		"gobco: warning: GOBCO_COVERDIR is not set, no coverage data emitted\n")
+}
+
+// Test binaries get GOBCO_STATS from gobco.
+// When they run without it, they don't mention GOBCO_COVERDIR,
+// as that variable only applies to programs from 'gobco build'.
+func Test_gobcoMain__test_without_stats(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco", "-keep", "./testdata/oddeven")
+	s.CheckContains(stdout, "Condition coverage: ")
+	prefix := "gobco: the temporary files are in "
+	s.CheckContains(stderr, prefix)
+	tmpdir := strings.TrimSpace(stderr[strings.Index(stderr, prefix)+len(prefix):])
+	defer func() { s.CheckEquals(os.RemoveAll(tmpdir), nil) }()
+
+	pkgDirs, err := filepath.Glob(filepath.Join(tmpdir, "*", "testdata", "oddeven"))
+	s.CheckEquals(err, nil)
+	s.CheckEquals(len(pkgDirs), 1)
+
+	cmd := exec.Command("go", "test", "-v")
+	cmd.Dir = pkgDirs[0]
+	cmd.Env = append(os.Environ(), "GOBCO_COVERDIR=", "GOBCO_STATS=")
+	out, err := cmd.CombinedOutput()
+	s.CheckEquals(err, nil)
+	s.CheckNotContains(string(out), "warning")
+}
+
+func Test_gobcoMain__report_no_stats(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+
+	stdout, stderr := s.RunMain(1, "gobco", "report", g.tmpdir)
+
+	s.CheckEquals(stdout, "")
+	s.CheckEquals(stderr, "no stats files found in "+g.tmpdir+"\n")
+}
+
+// Calls to os.Exit in the code under test persist the counts.
+func Test_gobcoMain__os_exit(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(1, "gobco", "-list-all", "./testdata/osexit")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+		"testdata/osexit/check.go:6:6: " +
+			"condition \"ok\" was once true and once false",
+	})
+	s.CheckContains(stderr, "exit status 1")
+}
+
+// Calls to log.Fatal in the code under test persist the counts.
+func Test_gobcoMain__log_fatal(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(1, "gobco", "-list-all", "./testdata/logfatal")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+		"testdata/logfatal/check.go:6:6: " +
+			"condition \"ok\" was once true and once false",
+	})
+	s.CheckContains(stderr, "exit status 1")
+}
+
+// A test process that is terminated by a signal persists the counts.
+func Test_gobcoMain__signal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows cannot send SIGTERM")
+	}
+
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(1, "gobco", "-list-all", "./testdata/signal")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+		"testdata/signal/small.go:4:9: " +
+			"condition \"i < 10\" was once true and once false",
+	})
+	s.CheckContains(stderr, "exit status 1")
+}
+
+// A panic in TestMain persists the counts.
+func Test_gobcoMain__panic(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(1, "gobco", "-list-all", "./testdata/panic")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+		"testdata/panic/small.go:4:9: " +
+			"condition \"i < 10\" was once true and once false",
+	})
+	s.CheckContains(stderr, "exit status 1")
+}
+
+// A panic in a test persists the counts,
+// even though the test runs in its own goroutine.
+func Test_gobcoMain__test_panic(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(1, "gobco", "-list-all", "./testdata/testpanic")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+		"testdata/testpanic/small.go:4:9: " +
+			"condition \"i < 10\" was once true and once false",
+	})
+	s.CheckContains(stderr, "exit status 1")
+}
+
+// A test that handles signals by itself gets each signal only once,
+// as gobco then doesn't intercept the signals.
+func Test_gobcoMain__own_signal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows cannot send SIGTERM")
+	}
+
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco", "-list-all", "./testdata/ownsignal")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+		"testdata/ownsignal/small.go:4:9: " +
+			"condition \"i < 10\" was once true and once false",
+	})
+	s.CheckEquals(stderr, "")
+}
+
+// With -record-tests, the stats file records which tests
+// cover each condition, and 'gobco report -who' lists them.
+func Test_gobcoMain__record_tests(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+	statsFilename := g.file("stats.json")
+
+	stdout, stderr := s.RunMain(0, "gobco",
+		"-record-tests", "-stats", statsFilename, "./testdata/who")
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 2/2",
+	})
+	s.CheckEquals(stderr, "")
+
+	stdout, stderr = s.RunMain(0, "gobco",
+		"report", "-who", "small.go:4:9", statsFilename)
+	s.CheckEquals(strings.Replace(stdout, "\\", "/", -1), ""+
+		"testdata/who/small.go:4:9: condition \"i < 10\"\n"+
+		"  true: Test_small\n"+
+		"  false: Test_subtest, Test_subtest/large\n")
+	s.CheckEquals(stderr, "")
+
+	stdout, stderr = s.RunMain(1, "gobco",
+		"report", "-who", "small.go:5:1", statsFilename)
+	s.CheckEquals(stdout, "")
+	s.CheckEquals(stderr, "no condition at small.go:5:1\n")
+}
+
+func Test_gobcoMain__minimize(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+	statsFilename := g.file("stats.json")
+
+	_, stderr := s.RunMain(0, "gobco",
+		"-record-tests", "-stats", statsFilename, "./testdata/minimize")
+	s.CheckEquals(stderr, "")
+
+	stdout, stderr := s.RunMain(0, "gobco", "minimize", statsFilename)
+
+	s.CheckEquals(stdout, ""+
+		"Selected 2 of 4 tests, covering all 4 covered outcomes:\n"+
+		"  Test_all\n"+
+		"  Test_zero\n"+
+		"Tests whose outcomes are covered by the selected tests:\n"+
+		"  Test_negative\n"+
+		"  Test_positive\n"+
+		"go test -run '^(Test_all|Test_zero)$'\n")
+	s.CheckEquals(stderr, "")
+}
+
+func Test_gobcoMain__minimize_without_tests(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+	statsFilename := g.file("stats.json")
+	writeFile(statsFilename, "[{\"Start\": \"a.go:1:1\", \"Code\": \"a\", "+
+		"\"TrueCount\": 1, \"FalseCount\": 0}]")
+
+	stdout, stderr := s.RunMain(1, "gobco", "minimize", statsFilename)
+
+	s.CheckEquals(stdout, "")
+	s.CheckEquals(stderr, "the stats files don't record any tests, "+
+		"run gobco with -record-tests\n")
+}
+
+func Test_gobcoMain__mutate(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco", "mutate", "./testdata/mutate")
+
+	s.CheckEquals(strings.Replace(stdout, "\\", "/", -1), ""+
+		"\n"+
+		"Mutation score: 1/2 killed\n"+
+		"testdata/mutate/mutate.go:8:5: "+
+		"negating condition \"i > 100\" did not fail any test\n")
+	s.CheckEquals(stderr, "")
+}
+
+// The mutants don't depend on an existing stats file,
+// and they run with the options for 'go test'.
+func Test_gobcoMain__mutate_stats_and_test_args(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+	statsFilename := g.file("stats.json")
+	_, stderr := s.RunMain(0, "gobco", "-stats", statsFilename, "./testdata/mutate")
+	s.CheckEquals(stderr, "")
+
+	stdout, stderr := s.RunMain(0, "gobco", "mutate",
+		"-stats", statsFilename, "-test", "-run=Test_isSmall",
+		"./testdata/mutate")
+
+	s.CheckEquals(stdout, ""+
+		"\n"+
+		"Mutation score: 1/1 killed\n")
+	s.CheckEquals(stderr, "")
+}
+
+func Test_gobcoMain__mutate_failing(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(1, "gobco", "mutate", "./testdata/failing")
+
+	s.CheckEquals(stdout, "")
+	s.CheckContains(stderr, "must pass before mutating")
+}
+
+// GOBCO_FORCE forces the outcome of a condition,
+// to explore the code paths that are hard to reach otherwise.
+func Test_gobcoMain__force(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	s.CheckEquals(os.Setenv("GOBCO_FORCE", "parse.go:6:5=true"), nil)
+	defer func() { _ = os.Unsetenv("GOBCO_FORCE") }()
+
+	stdout, stderr := s.RunMain(0, "gobco", "./testdata/force")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 0/2",
+		"testdata/force/parse.go:6:5: condition \"len(s) == 0\" was never evaluated",
+		"testdata/force/parse.go:6:5: condition \"len(s) == 0\" was forced once to true",
+	})
+	s.CheckEquals(stderr, "")
+}
+
+func Test_gobcoMain__html(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	htmlFilename := filepath.Join(t.TempDir(), "coverage.html")
+
+	stdout, stderr := s.RunMain(0, "gobco", "-html", htmlFilename, "testdata/oddeven")
+
+	s.CheckContains(stdout, "Condition coverage: 0/2")
+	s.CheckEquals(stderr, "")
+
+	content, err := os.ReadFile(htmlFilename)
+	s.CheckEquals(err, nil)
+	s.CheckContains(string(content),
+		"<td><a href=\"#file0\">testdata/oddeven/odd.go</a></td><td>0/2</td><td>0.0%</td>")
+	s.CheckContains(string(content), ""+
+		"\treturn <span class=\"never\" "+
+		"title=\"x%2 != 0: 0 times true, 0 times false\">x%2 != 0</span>\n")
+}
+
+func Test_gobcoMain__cobertura(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	xmlFilename := filepath.Join(t.TempDir(), "coverage.xml")
+
+	stdout, stderr := s.RunMain(0, "gobco",
+		"-format", "cobertura", "-o", xmlFilename, "testdata/oddeven")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 0/2",
+		"testdata/oddeven/odd.go:4:9: condition \"x%2 != 0\" was never evaluated",
+	})
+	s.CheckEquals(stderr, "")
+
+	content, err := os.ReadFile(xmlFilename)
+	s.CheckEquals(err, nil)
+	s.CheckEquals(normalizeTime(string(content)), ""+
+		"<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"+
+		"<!DOCTYPE coverage SYSTEM \"http://cobertura.sourceforge.net/xml/coverage-04.dtd\">\n"+
+		"<coverage line-rate=\"0\" branch-rate=\"0\" lines-covered=\"0\" lines-valid=\"1\" "+
+		"branches-covered=\"0\" branches-valid=\"2\" complexity=\"0\" "+
+		"version=\"gobco "+version+"\" timestamp=\"0\">\n"+
+		"\t<sources>\n"+
+		"\t\t<source>.</source>\n"+
+		"\t</sources>\n"+
+		"\t<packages>\n"+
+		"\t\t<package name=\"testdata/oddeven\" line-rate=\"0\" branch-rate=\"0\" complexity=\"0\">\n"+
+		"\t\t\t<classes>\n"+
+		"\t\t\t\t<class name=\"odd.go\" filename=\"testdata/oddeven/odd.go\" line-rate=\"0\" branch-rate=\"0\" complexity=\"0\">\n"+
+		"\t\t\t\t\t<methods></methods>\n"+
+		"\t\t\t\t\t<lines>\n"+
+		"\t\t\t\t\t\t<line number=\"4\" hits=\"0\" branch=\"true\" condition-coverage=\"0% (0/2)\"></line>\n"+
+		"\t\t\t\t\t</lines>\n"+
+		"\t\t\t\t</class>\n"+
+		"\t\t\t</classes>\n"+
+		"\t\t</package>\n"+
+		"\t</packages>\n"+
+		"</coverage>\n")
+}
+
+// Without -o, the report from -format replaces the text output,
+// and the output of the tests goes to stderr.
+func Test_gobcoMain__lcov(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco", "-format", "lcov", "testdata/oddeven")
+
+	s.CheckEquals(stdout, ""+
+		"TN:\n"+
+		"SF:testdata/oddeven/odd.go\n"+
+		"BRDA:4,0,0,-\n"+
+		"BRDA:4,0,1,-\n"+
+		"BRF:2\n"+
+		"BRH:0\n"+
+		"end_of_record\n")
+	s.CheckContains(stderr, "ok  \tgithub.com/rillig/gobco/testdata/oddeven\t")
+}
+
+func Test_gobcoMain__json(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco", "-format", "json", "testdata/oddeven")
+
+	s.CheckEquals(normalizeTime(stdout), ""+
+		"{\n"+
+		"\t\"schemaVersion\": 1,\n"+
+		"\t\"metadata\": {\n"+
+		"\t\t\"tool\": \"gobco\",\n"+
+		"\t\t\"version\": \""+version+"\",\n"+
+		"\t\t\"mode\": \"condition\",\n"+
+		"\t\t\"generated\": \"2000-01-01T00:00:00Z\"\n"+
+		"\t},\n"+
+		"\t\"totals\": {\n"+
+		"\t\t\"conditions\": 1,\n"+
+		"\t\t\"covered\": 0,\n"+
+		"\t\t\"total\": 2,\n"+
+		"\t\t\"percent\": 0\n"+
+		"\t},\n"+
+		"\t\"packages\": [\n"+
+		"\t\t{\n"+
+		"\t\t\t\"name\": \"testdata/oddeven\",\n"+
+		"\t\t\t\"conditions\": 1,\n"+
+		"\t\t\t\"covered\": 0,\n"+
+		"\t\t\t\"total\": 2,\n"+
+		"\t\t\t\"percent\": 0\n"+
+		"\t\t}\n"+
+		"\t],\n"+
+		"\t\"files\": [\n"+
+		"\t\t{\n"+
+		"\t\t\t\"name\": \"testdata/oddeven/odd.go\",\n"+
+		"\t\t\t\"package\": \"testdata/oddeven\",\n"+
+		"\t\t\t\"conditions\": 1,\n"+
+		"\t\t\t\"covered\": 0,\n"+
+		"\t\t\t\"total\": 2,\n"+
+		"\t\t\t\"percent\": 0\n"+
+		"\t\t}\n"+
+		"\t],\n"+
+		"\t\"functions\": [\n"+
+		"\t\t{\n"+
+		"\t\t\t\"name\": \"IsOdd\",\n"+
+		"\t\t\t\"file\": \"testdata/oddeven/odd.go\",\n"+
+		"\t\t\t\"conditions\": 1,\n"+
+		"\t\t\t\"covered\": 0,\n"+
+		"\t\t\t\"total\": 2,\n"+
+		"\t\t\t\"percent\": 0\n"+
+		"\t\t}\n"+
+		"\t],\n"+
+		"\t\"conditions\": [\n"+
+		"\t\t{\n"+
+		"\t\t\t\"start\": \"testdata/oddeven/odd.go:4:9\",\n"+
+		"\t\t\t\"end\": \"testdata/oddeven/odd.go:4:17\",\n"+
+		"\t\t\t\"code\": \"x%2 != 0\",\n"+
+		"\t\t\t\"class\": \"const\",\n"+
+		"\t\t\t\"func\": \"IsOdd\",\n"+
+		"\t\t\t\"trueCount\": 0,\n"+
+		"\t\t\t\"falseCount\": 0\n"+
+		"\t\t}\n"+
+		"\t]\n"+
+		"}\n")
+	s.CheckContains(stderr, "ok  \tgithub.com/rillig/gobco/testdata/oddeven\t")
+}
+
+func Test_gobcoMain__sarif(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco", "-format", "sarif", "testdata/oddeven")
+
+	s.CheckEquals(stdout, ""+
+		"{\n"+
+		"  \"$schema\": \"https://json.schemastore.org/sarif-2.1.0.json\",\n"+
+		"  \"version\": \"2.1.0\",\n"+
+		"  \"runs\": [\n"+
+		"    {\n"+
+		"      \"tool\": {\n"+
+		"        \"driver\": {\n"+
+		"          \"name\": \"gobco\",\n"+
+		"          \"version\": \""+version+"\",\n"+
+		"          \"informationUri\": \"https://github.com/rillig/gobco\",\n"+
+		"          \"rules\": [\n"+
+		"            {\n"+
+		"              \"id\": \"never-evaluated\",\n"+
+		"              \"shortDescription\": {\n"+
+		"                \"text\": \"The condition was never evaluated.\"\n"+
+		"              }\n"+
+		"            },\n"+
+		"            {\n"+
+		"              \"id\": \"never-true\",\n"+
+		"              \"shortDescription\": {\n"+
+		"                \"text\": \"The condition was never true.\"\n"+
+		"              }\n"+
+		"            },\n"+
+		"            {\n"+
+		"              \"id\": \"never-false\",\n"+
+		"              \"shortDescription\": {\n"+
+		"                \"text\": \"The condition was never false.\"\n"+
+		"              }\n"+
+		"            }\n"+
+		"          ]\n"+
+		"        }\n"+
+		"      },\n"+
+		"      \"results\": [\n"+
+		"        {\n"+
+		"          \"ruleId\": \"never-evaluated\",\n"+
+		"          \"level\": \"warning\",\n"+
+		"          \"message\": {\n"+
+		"            \"text\": \"condition \\\"x%2 != 0\\\" was never evaluated\"\n"+
+		"          },\n"+
+		"          \"locations\": [\n"+
+		"            {\n"+
+		"              \"physicalLocation\": {\n"+
+		"                \"artifactLocation\": {\n"+
+		"                  \"uri\": \"testdata/oddeven/odd.go\"\n"+
+		"                },\n"+
+		"                \"region\": {\n"+
+		"                  \"startLine\": 4,\n"+
+		"                  \"startColumn\": 9,\n"+
+		"                  \"endLine\": 4,\n"+
+		"                  \"endColumn\": 17\n"+
+		"                }\n"+
+		"              }\n"+
+		"            }\n"+
+		"          ],\n"+
+		"          \"properties\": {\n"+
+		"            \"code\": \"x%2 != 0\",\n"+
+		"            \"class\": \"const\",\n"+
+		"            \"trueCount\": 0,\n"+
+		"            \"falseCount\": 0\n"+
+		"          }\n"+
+		"        }\n"+
+		"      ]\n"+
+		"    }\n"+
+		"  ]\n"+
+		"}\n")
+	s.CheckContains(stderr, "ok  \tgithub.com/rillig/gobco/testdata/oddeven\t")
+}
+
+func Test_gobcoMain__markdown(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco", "-format", "markdown", "testdata/oddeven")
+
+	s.CheckEquals(stdout, ""+
+		"## Condition coverage: 0.0%\n"+
+		"\n"+
+		"| File | Covered | Coverage |\n"+
+		"|:-----|--------:|---------:|\n"+
+		"| `testdata/oddeven/odd.go` | 0/2 | 0.0% |\n"+
+		"| **Total** | **0/2** | **0.0%** |\n"+
+		"\n"+
+		"<details>\n"+
+		"<summary>2 uncovered outcomes in 1 files</summary>\n"+
+		"\n"+
+		"#### `testdata/oddeven/odd.go`\n"+
+		"\n"+
+		"- [testdata/oddeven/odd.go:4:9](testdata/oddeven/odd.go#L4): "+
+		"condition \"x%2 != 0\" was never evaluated\n"+
+		"\n"+
+		"</details>\n")
+	s.CheckContains(stderr, "ok  \tgithub.com/rillig/gobco/testdata/oddeven\t")
+}
+
+// normalizeTime replaces the timestamps in the reports
+// with a fixed value, to make them comparable.
+func normalizeTime(report string) string {
+	report = regexp.MustCompile(`"generated": "[^"]*"`).
+		ReplaceAllString(report, `"generated": "2000-01-01T00:00:00Z"`)
+	return regexp.MustCompile(` timestamp="\d+"`).
+		ReplaceAllString(report, ` timestamp="0"`)
+}
+
+func Test_gobcoMain__summary(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco", "-summary", "func", "testdata/oddeven")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 0/2",
+		"",
+		"File                     Function  Covered  Coverage  Uncovered",
+		"testdata/oddeven/odd.go  IsOdd     0/2      0.0%      2",
+		"testdata/oddeven/odd.go:4:9: condition \"x%2 != 0\" was never evaluated",
+	})
+	s.CheckEquals(stderr, "")
+}
+
+func Test_gobcoMain__show(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(0, "gobco", "show", "testdata/oddeven/odd.go")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 0/2",
+		"",
+		"testdata/oddeven/odd.go:",
+		"1 | package simple",
+		"2 |",
+		"3 | func IsOdd(x int) bool {",
+		"4 | \treturn x%2 != 0",
+		"  | \t       ^^^^^^^^ never evaluated",
+		"5 | }",
+	})
+	s.CheckEquals(stderr, "")
+}
+
+func Test_gobcoMain__show_function(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	stdout, stderr := s.RunMain(1, "gobco", "-show", "IsEven", "testdata/oddeven")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 0/2",
+	})
+	s.CheckEquals(stderr, "function IsEven not found\n")
+
+	stdout, stderr = s.RunMain(0, "gobco", "-show", "IsOdd", "testdata/oddeven")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 0/2",
+		"",
+		"testdata/oddeven/odd.go:",
+		"3 | func IsOdd(x int) bool {",
+		"4 | \treturn x%2 != 0",
+		"  | \t       ^^^^^^^^ never evaluated",
+		"5 | }",
+	})
+	s.CheckEquals(stderr, "")
+}
+
+func Test_gobcoMain__markdown_compare(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+	oldFilename := g.file("old.json")
+	newFilename := g.file("new.json")
+	writeFile(oldFilename, "[{\"Start\": \"a.go:1:1\", \"Code\": \"a\", "+
+		"\"TrueCount\": 1, \"FalseCount\": 0}]")
+	writeFile(newFilename, "[{\"Start\": \"a.go:1:1\", \"Code\": \"a\", "+
+		"\"TrueCount\": 1, \"FalseCount\": 1}]")
+
+	stdout, stderr := s.RunMain(0, "gobco", "report",
+		"-format", "markdown", "-compare", oldFilename, newFilename)
+
+	s.CheckEquals(stdout, ""+
+		"## Condition coverage: 100.0%\n"+
+		"\n"+
+		"| File | Covered | Coverage |\n"+
+		"|:-----|--------:|---------:|\n"+
+		"| `a.go` | 2/2 | 100.0% |\n"+
+		"| **Total** | **2/2** | **100.0%** |\n"+
+		"\n"+
+		"### Changes since the previous run\n"+
+		"\n"+
+		"| File | Previous | Current | Change |\n"+
+		"|:-----|---------:|--------:|-------:|\n"+
+		"| `a.go` | 1/2 | 2/2 | +50.0% |\n"+
+		"| **Total** | **1/2** | **2/2** | **+50.0%** |\n")
+	s.CheckEquals(stderr, "")
+}
+
+func Test_gobcoMain__diff(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+	oldFilename := g.file("old.json")
+	newFilename := g.file("new.json")
+	writeFile(oldFilename, "["+
+		"{\"Start\": \"a.go:1:5\", \"Code\": \"a\", \"TrueCount\": 1, \"FalseCount\": 1},"+
+		"{\"Start\": \"a.go:2:5\", \"Code\": \"b\", \"TrueCount\": 0, \"FalseCount\": 1},"+
+		"{\"Start\": \"a.go:3:5\", \"Code\": \"c\", \"TrueCount\": 0, \"FalseCount\": 1}]")
+	writeFile(newFilename, "["+
+		"{\"Start\": \"a.go:2:5\", \"Code\": \"a\", \"TrueCount\": 1, \"FalseCount\": 0},"+
+		"{\"Start\": \"a.go:3:5\", \"Code\": \"b\", \"TrueCount\": 1, \"FalseCount\": 1},"+
+		"{\"Start\": \"a.go:4:5\", \"Code\": \"d\", \"TrueCount\": 0, \"FalseCount\": 0}]")
+
+	stdout, stderr := s.RunMain(1, "gobco", "diff", oldFilename, newFilename)
+
+	s.CheckEquals(stdout, ""+
+		"Condition coverage: 4/6 (66.7%) -> 3/6 (50.0%)\n"+
+		"No longer covered:\n"+
+		"  a.go:2:5: condition \"a\" is no longer false\n"+
+		"Newly covered:\n"+
+		"  a.go:3:5: condition \"b\" is now true\n"+
+		"Added:\n"+
+		"  a.go:4:5: condition \"d\"\n"+
+		"Removed:\n"+
+		"  a.go:3:5: condition \"c\"\n")
+	s.CheckEquals(stderr, "1 outcomes are no longer covered\n")
+
+	stdout, stderr = s.RunMain(0, "gobco", "diff", oldFilename, oldFilename)
+
+	s.CheckEquals(stdout, "Condition coverage: 4/6 (66.7%) -> 4/6 (66.7%)\n")
+	s.CheckEquals(stderr, "")
+}
+
+func Test_gobcoMain__baseline(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+	baseline := g.file("baseline.json")
+
+	// Without an existing baseline, -update-baseline creates it.
+	_, stderr := s.RunMain(0, "gobco",
+		"-baseline", baseline, "-update-baseline", "testdata/issue38")
+
+	s.CheckEquals(stderr, "updated the baseline "+baseline+"\n")
+	conds, err := g.load(baseline)
+	s.CheckEquals(err, nil)
+	s.CheckEquals(conds, []condition{
+		{
+			Start:      "testdata/issue38/main.go:4:5",
+			Code:       "a >= 0",
+			TrueCount:  1,
+			FalseCount: 0,
+			Class:      "const",
+			End:        "testdata/issue38/main.go:4:11",
+			Func:       "Abs",
+		},
+	})
+
+	// The coverage didn't change, so the baseline stays the same.
+	_, stderr = s.RunMain(0, "gobco",
+		"-baseline", baseline, "-update-baseline", "testdata/issue38")
+
+	s.CheckEquals(stderr, "")
+
+	// An outcome that is covered in the baseline must remain covered.
+	writeFile(baseline, "[{\"Start\": \"testdata/issue38/main.go:4:5\", "+
+		"\"Code\": \"a >= 0\", \"TrueCount\": 1, \"FalseCount\": 1}]")
+
+	_, stderr = s.RunMain(1, "gobco", "-baseline", baseline, "testdata/issue38")
+
+	s.CheckEquals(stderr, ""+
+		"testdata/issue38/main.go:4:5: condition \"a >= 0\" is no longer false\n"+
+		"1 outcomes that are covered in the baseline "+baseline+" are no longer covered\n")
+}
+
+// With -update-baseline, the baseline is only rewritten
+// if the coverage improved, not whenever conditions are removed.
+func Test_gobco_checkBaseline__update(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+	g.baseline = g.file("baseline.json")
+	g.updateBaseline = true
+	conds := []condition{
+		{Start: "a.go:1:5", Code: "a", TrueCount: 1, FalseCount: 0, Func: "F"},
+	}
+
+	test := func(baseline string, updated bool) {
+		writeFile(g.baseline, baseline)
+
+		g.checkBaseline(conds)
+
+		if updated {
+			s.CheckEquals(s.Stderr(), "updated the baseline "+g.baseline+"\n")
+		} else {
+			s.CheckEquals(s.Stderr(), "")
+		}
+		s.CheckEquals(g.exitCode, 0)
+	}
+
+	// A covered condition was removed, which lowers the coverage.
+	test("["+
+		"{\"Start\": \"a.go:1:5\", \"Code\": \"a\", \"TrueCount\": 1, \"FalseCount\": 0},"+
+		"{\"Start\": \"a.go:2:5\", \"Code\": \"b\", \"TrueCount\": 1, \"FalseCount\": 1}"+
+		"]", false)
+
+	// An uncovered condition was removed, which raises the coverage.
+	test("["+
+		"{\"Start\": \"a.go:1:5\", \"Code\": \"a\", \"TrueCount\": 1, \"FalseCount\": 0},"+
+		"{\"Start\": \"a.go:2:5\", \"Code\": \"b\", \"TrueCount\": 0, \"FalseCount\": 0}"+
+		"]", true)
+
+	// An outcome is newly covered.
+	test("["+
+		"{\"Start\": \"a.go:1:5\", \"Code\": \"a\", \"TrueCount\": 0, \"FalseCount\": 0}"+
+		"]", true)
+}
+
+func Test_addCounts(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	conds := []condition{{Start: "a.go:1:1", Code: "a", TrueCount: 1, FalseCount: 2}}
+
+	test := func(counts string, expected []condition, expectedErr error) {
+		actual, err := addCounts(conds, []byte(counts))
+		s.CheckEquals(actual, expected)
+		s.CheckEquals(err, expectedErr)
+	}
+
+	test("gobco",
+		nil, fmt.Errorf("invalid header"))
+	test("gobcocnt\x02\x00\x00\x00\x00\x00\x00\x00",
+		nil, fmt.Errorf("invalid number of counters 2"))
+	test("gobcocnt\x00\x00\x00\x00\x00\x00\x00\x00"+`[["a.go:1:1","a"]]`,
+		nil, fmt.Errorf("must have exactly 2 counters"))
+
+	counts := "gobcocnt" +
+		"\x04\x00\x00\x00\x00\x00\x00\x00" +
+		"\x05\x00\x00\x00\x00\x00\x00\x00" +
+		"\x00\x01\x00\x00\x00\x00\x00\x00" +
+		"\x01\x00\x00\x00\x00\x00\x00\x00" +
+		"\x00\x00\x00\x00\x00\x00\x00\x00" +
+		`[["b.go:1:1","b"],["a.go:1:1","a"]]`
+	test(counts,
+		[]condition{
+			{Start: "a.go:1:1", Code: "a", TrueCount: 2, FalseCount: 2},
+			{Start: "b.go:1:1", Code: "b", TrueCount: 5, FalseCount: 256},
+		},
+		nil)
+}
+
+func Test_gobco_load__corrupt(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+	filename := g.file("corrupt.json")
+	writeFile(filename, "[{\"Start\": \"a.go:1:1\",")
+
+	conds, err := g.load(filename)
+
+	s.CheckEquals(conds, []condition(nil))
+	s.CheckEquals(err.Error(), "the stats file "+filename+
+		" is corrupt or incomplete: unexpected EOF")
+}
+
+func Test_gobco_persist(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+	filename := g.file("stats.json")
+	conds := []condition{{Start: "a.go:1:1", Code: "a", TrueCount: 5, FalseCount: 0, Class: "other"}}
+
+	s.CheckEquals(g.persist(filename, conds), nil)
+	loaded, err := g.load(filename)
+
+	s.CheckEquals(err, nil)
+	s.CheckEquals(loaded, conds)
+	s.CheckEquals(listRegularFiles(g.tmpdir), []string{"stats.json"})
+
+	// The stats file has the same permissions as a file from os.Create,
+	// not only the owner permissions from os.CreateTemp.
+	writeFile(g.file("created.json"), "")
+	created, err := os.Stat(g.file("created.json"))
+	s.CheckEquals(err, nil)
+	persisted, err := os.Stat(filename)
+	s.CheckEquals(err, nil)
+	s.CheckEquals(persisted.Mode().Perm(), created.Mode().Perm())
+}
+
+// A corrupt stats file, for example from an interrupted run,
+// is ignored and then overwritten.
+func Test_gobcoMain__corrupt_stats(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+	statsFilename := g.file("stats.json")
+	writeFile(statsFilename, "[{\"Start\": \"a.go:1:1\",")
+	created, err := os.Stat(statsFilename)
+	s.CheckEquals(err, nil)
+
+	stdout, stderr := s.RunMain(0, "gobco",
+		"-stats", statsFilename, "./testdata/issue38")
+
+	s.CheckEquals(s.GobcoLines(stdout), []string{
+		"Condition coverage: 1/2",
+		"testdata/issue38/main.go:4:5: " +
+			"condition \"a >= 0\" was 2 times true but never false",
+	})
+	s.CheckEquals(stderr, "")
+	_, err = g.load(statsFilename)
+	s.CheckEquals(err, nil)
+
+	// The replaced stats file keeps the usual permissions.
+	persisted, err := os.Stat(statsFilename)
+	s.CheckEquals(err, nil)
+	s.CheckEquals(persisted.Mode().Perm(), created.Mode().Perm())
+}
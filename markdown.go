@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// writeMarkdown writes a compact report for code review comments,
+// consisting of a summary table, the uncovered conditions grouped by file,
+// and, if previous is given, the changes since the previous run.
+func (g *gobco) writeMarkdown(w io.Writer, conds, previous []condition) error {
+	var sb strings.Builder
+	line := func(format string, args ...interface{}) {
+		sb.WriteString(fmt.Sprintf(format, args...))
+		sb.WriteString("\n")
+	}
+
+	files := condsByFile(conds)
+	covered, total := 0, 0
+	for _, file := range files {
+		cov, outcomes := file.outcomes()
+		covered += cov
+		total += outcomes
+	}
+
+	kind := "Condition coverage"
+	if g.branch {
+		kind = "Branch coverage"
+	}
+	line("## %s: %s", kind, percentage(covered, total))
+	line("")
+	line("| File | Covered | Coverage |")
+	line("|:-----|--------:|---------:|")
+	for _, file := range files {
+		cov, outcomes := file.outcomes()
+		line("| `%s` | %d/%d | %s |",
+			filepath.ToSlash(file.name), cov, outcomes, percentage(cov, outcomes))
+	}
+	line("| **Total** | **%d/%d** | **%s** |",
+		covered, total, percentage(covered, total))
+
+	if previous != nil {
+		line("")
+		g.writeMarkdownDelta(line, files, condsByFile(previous))
+	}
+
+	// Collect the uncovered conditions first,
+	// as the -class option may filter out all conditions of a file.
+	type uncoveredFile struct {
+		name  string
+		items []string
+	}
+	var uncovered []uncoveredFile
+	uncoveredOutcomes := 0
+	for _, file := range files {
+		items, outcomes := g.markdownUncovered(file)
+		if len(items) > 0 {
+			uncovered = append(uncovered, uncoveredFile{filepath.ToSlash(file.name), items})
+			uncoveredOutcomes += outcomes
+		}
+	}
+	if len(uncovered) > 0 {
+		line("")
+		line("<details>")
+		line("<summary>%d uncovered outcomes in %d files</summary>",
+			uncoveredOutcomes, len(uncovered))
+		for _, file := range uncovered {
+			line("")
+			line("#### `%s`", file.name)
+			line("")
+			for _, item := range file.items {
+				line("%s", item)
+			}
+		}
+		line("")
+		line("</details>")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeMarkdownDelta writes a table with the files whose coverage
+// changed since the previous run.
+func (g *gobco) writeMarkdownDelta(
+	line func(string, ...interface{}),
+	files, previous []fileConds,
+) {
+	type counts struct{ prevCovered, prevTotal, covered, total int }
+	byName := map[string]*counts{}
+	var names []string
+	get := func(name string) *counts {
+		if byName[name] == nil {
+			byName[name] = &counts{}
+			names = append(names, name)
+		}
+		return byName[name]
+	}
+	for _, file := range previous {
+		c := get(file.name)
+		c.prevCovered, c.prevTotal = file.outcomes()
+	}
+	for _, file := range files {
+		c := get(file.name)
+		c.covered, c.total = file.outcomes()
+	}
+
+	var sum counts
+	var rows []string
+	sort.Strings(names)
+	for _, name := range names {
+		c := byName[name]
+		sum.prevCovered += c.prevCovered
+		sum.prevTotal += c.prevTotal
+		sum.covered += c.covered
+		sum.total += c.total
+		if c.covered != c.prevCovered || c.total != c.prevTotal {
+			rows = append(rows, fmt.Sprintf("| `%s` | %d/%d | %d/%d | %s |",
+				filepath.ToSlash(name), c.prevCovered, c.prevTotal,
+				c.covered, c.total, delta(c.prevCovered, c.prevTotal, c.covered, c.total)))
+		}
+	}
+
+	line("### Changes since the previous run")
+	line("")
+	if len(rows) == 0 {
+		line("The coverage did not change.")
+		return
+	}
+	line("| File | Previous | Current | Change |")
+	line("|:-----|---------:|--------:|-------:|")
+	for _, row := range rows {
+		line("%s", row)
+	}
+	line("| **Total** | **%d/%d** | **%d/%d** | **%s** |",
+		sum.prevCovered, sum.prevTotal, sum.covered, sum.total,
+		delta(sum.prevCovered, sum.prevTotal, sum.covered, sum.total))
+}
+
+// markdownUncovered returns the list items for the conditions of the file
+// that are not fully covered, using the wording from printCond,
+// with the locations linked to the source.
+// It also returns the number of uncovered outcomes of these conditions.
+func (g *gobco) markdownUncovered(file fileConds) (items []string, uncovered int) {
+	name := filepath.ToSlash(file.name)
+	for _, cond := range file.conds {
+		covered, outcomes := cond.outcomes()
+		if covered == outcomes {
+			continue
+		}
+		msgs := g.describeCond(cond)
+		if len(msgs) == 0 {
+			continue
+		}
+		uncovered += outcomes - covered
+
+		pos, _ := parsePosition(cond.Start)
+		link := fmt.Sprintf("[%s](%s#L%d)",
+			markdownEscape(filepath.ToSlash(cond.Start)), name, pos.line)
+		for _, msg := range msgs {
+			i := strings.Index(msg, cond.Start)
+			if i < 0 {
+				items = append(items, "- "+markdownEscape(msg))
+				continue
+			}
+			before, after := msg[:i], msg[i+len(cond.Start):]
+			items = append(items, "- "+markdownEscape(before)+link+markdownEscape(after))
+		}
+	}
+	return
+}
+
+// delta formats the change in the coverage percentage,
+// such as "+2.5%" or "-1.0%".
+func delta(prevCovered, prevTotal, covered, total int) string {
+	if prevTotal == 0 {
+		return "new"
+	}
+	if total == 0 {
+		return "removed"
+	}
+	diff := 100 * (rate(covered, total) - rate(prevCovered, prevTotal))
+	return fmt.Sprintf("%+.1f%%", diff)
+}
+
+// markdownEscape escapes the characters that have a special meaning
+// in Markdown text.
+func markdownEscape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("\\`*_[]<>|#", r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
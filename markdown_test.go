@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_gobco_writeMarkdown(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+	conds := []condition{
+		{Start: "pkg/a.go:3:5", Code: "a", TrueCount: 2, FalseCount: 1},
+		{Start: "pkg/a.go:7:5", Code: "b*c", TrueCount: 2, FalseCount: 0},
+		{Start: "pkg/b.go:4:2", Code: "return 0", TrueCount: 0, FalseCount: 0, Kind: "return", Class: "return"},
+	}
+	previous := []condition{
+		{Start: "pkg/a.go:3:5", Code: "a", TrueCount: 2, FalseCount: 1},
+		{Start: "pkg/a.go:7:5", Code: "b*c", TrueCount: 0, FalseCount: 0},
+	}
+
+	var sb strings.Builder
+	err := g.writeMarkdown(&sb, conds, previous)
+
+	s.CheckEquals(err, nil)
+	s.CheckEquals(sb.String(), ""+
+		"## Condition coverage: 60.0%\n"+
+		"\n"+
+		"| File | Covered | Coverage |\n"+
+		"|:-----|--------:|---------:|\n"+
+		"| `pkg/a.go` | 3/4 | 75.0% |\n"+
+		"| `pkg/b.go` | 0/1 | 0.0% |\n"+
+		"| **Total** | **3/5** | **60.0%** |\n"+
+		"\n"+
+		"### Changes since the previous run\n"+
+		"\n"+
+		"| File | Previous | Current | Change |\n"+
+		"|:-----|---------:|--------:|-------:|\n"+
+		"| `pkg/a.go` | 2/4 | 3/4 | +25.0% |\n"+
+		"| `pkg/b.go` | 0/0 | 0/1 | new |\n"+
+		"| **Total** | **2/4** | **3/5** | **+10.0%** |\n"+
+		"\n"+
+		"<details>\n"+
+		"<summary>2 uncovered outcomes in 2 files</summary>\n"+
+		"\n"+
+		"#### `pkg/a.go`\n"+
+		"\n"+
+		"- [pkg/a.go:7:5](pkg/a.go#L7): condition \"b\\*c\" was 2 times true but never false\n"+
+		"\n"+
+		"#### `pkg/b.go`\n"+
+		"\n"+
+		"- return at [pkg/b.go:4:2](pkg/b.go#L4) was never executed\n"+
+		"\n"+
+		"</details>\n")
+}
+
+// With -class, only the conditions of that class are listed,
+// and files without such conditions are omitted.
+func Test_gobco_writeMarkdown__class(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	g := s.newGobco()
+	g.classes = []string{"error"}
+	conds := []condition{
+		{Start: "pkg/a.go:3:5", Code: "err != nil", TrueCount: 2, FalseCount: 0, Class: "error"},
+		{Start: "pkg/a.go:7:5", Code: "b", TrueCount: 0, FalseCount: 0, Class: "other"},
+		{Start: "pkg/b.go:4:2", Code: "c", TrueCount: 0, FalseCount: 0, Class: "other"},
+	}
+
+	var sb strings.Builder
+	err := g.writeMarkdown(&sb, conds, nil)
+
+	s.CheckEquals(err, nil)
+	s.CheckEquals(sb.String(), ""+
+		"## Condition coverage: 16.7%\n"+
+		"\n"+
+		"| File | Covered | Coverage |\n"+
+		"|:-----|--------:|---------:|\n"+
+		"| `pkg/a.go` | 1/4 | 25.0% |\n"+
+		"| `pkg/b.go` | 0/2 | 0.0% |\n"+
+		"| **Total** | **1/6** | **16.7%** |\n"+
+		"\n"+
+		"<details>\n"+
+		"<summary>1 uncovered outcomes in 1 files</summary>\n"+
+		"\n"+
+		"#### `pkg/a.go`\n"+
+		"\n"+
+		"- [pkg/a.go:3:5](pkg/a.go#L3): condition \"err != nil\" was 2 times true but never false\n"+
+		"\n"+
+		"</details>\n")
+}
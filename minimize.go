@@ -0,0 +1,137 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// minimize selects a small set of tests that covers the same condition
+// outcomes as the whole test suite,
+// based on the tests that were recorded using -record-tests.
+//
+// Subtests are attributed to their top-level test,
+// since 'go test -run' selects the top-level tests.
+func (g *gobco) minimize() {
+	conds, err := g.loadReportArgs()
+	if err != nil {
+		g.errf("%s", err)
+		g.exitCode = 1
+		return
+	}
+
+	tests := testOutcomes(conds)
+	if len(tests) == 0 {
+		g.errf("the stats files don't record any tests, " +
+			"run gobco with -record-tests")
+		g.exitCode = 1
+		return
+	}
+
+	selected, total := minimalTests(tests)
+	g.outf("Selected %d of %d tests, covering all %d covered outcomes:",
+		len(selected), len(tests), total)
+	for _, test := range selected {
+		g.outf("  %s", test)
+	}
+
+	redundant := redundantTests(tests, selected)
+	if len(redundant) > 0 {
+		g.outf("Tests whose outcomes are covered by the selected tests:")
+		for _, test := range redundant {
+			g.outf("  %s", test)
+		}
+	}
+
+	g.outf("go test -run '^(%s)$'", strings.Join(selected, "|"))
+}
+
+// outcome is one of the possible outcomes of a condition,
+// identified by the index of the condition and the outcome.
+type outcome struct {
+	cond  int
+	value bool
+}
+
+// testOutcomes returns for each top-level test the outcomes it covers.
+func testOutcomes(conds []condition) map[string]map[outcome]bool {
+	tests := map[string]map[outcome]bool{}
+	add := func(names []string, o outcome) {
+		for _, name := range names {
+			name = strings.SplitN(name, "/", 2)[0]
+			if tests[name] == nil {
+				tests[name] = map[outcome]bool{}
+			}
+			tests[name][o] = true
+		}
+	}
+
+	for i, cond := range conds {
+		add(cond.TrueTests, outcome{i, true})
+		add(cond.FalseTests, outcome{i, false})
+	}
+	return tests
+}
+
+// minimalTests greedily selects the tests that cover the most outcomes
+// that are not yet covered, until all outcomes are covered.
+// Although the result is not necessarily the smallest possible set,
+// it is typically close to it.
+func minimalTests(tests map[string]map[outcome]bool) (selected []string, total int) {
+	uncovered := map[outcome]bool{}
+	for _, outcomes := range tests {
+		for o := range outcomes {
+			uncovered[o] = true
+		}
+	}
+	total = len(uncovered)
+
+	names := sortedTestNames(tests)
+	for len(uncovered) > 0 {
+		best, bestCount := "", 0
+		for _, name := range names {
+			count := 0
+			for o := range tests[name] {
+				if uncovered[o] {
+					count++
+				}
+			}
+			if count > bestCount {
+				best, bestCount = name, count
+			}
+		}
+
+		selected = append(selected, best)
+		for o := range tests[best] {
+			delete(uncovered, o)
+		}
+	}
+
+	sort.Strings(selected)
+	return
+}
+
+// redundantTests returns the tests that are not selected,
+// as their outcomes are all covered by the selected tests.
+func redundantTests(tests map[string]map[outcome]bool, selected []string) []string {
+	isSelected := map[string]bool{}
+	for _, name := range selected {
+		isSelected[name] = true
+	}
+
+	var redundant []string
+	for _, name := range sortedTestNames(tests) {
+		if !isSelected[name] {
+			redundant = append(redundant, name)
+		}
+	}
+	return redundant
+}
+
+func sortedTestNames(tests map[string]map[outcome]bool) []string {
+	var names []string
+	for name := range tests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
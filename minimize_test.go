@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_minimalTests(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	tests := map[string]map[outcome]bool{
+		"TestA": {{0, true}: true, {1, true}: true},
+		"TestB": {{0, true}: true, {0, false}: true, {1, true}: true},
+		"TestC": {{1, false}: true},
+		"TestD": {{0, false}: true},
+	}
+
+	selected, total := minimalTests(tests)
+
+	s.CheckEquals(selected, []string{"TestB", "TestC"})
+	s.CheckEquals(total, 4)
+	s.CheckEquals(redundantTests(tests, selected), []string{"TestA", "TestD"})
+}
+
+// Each test is either selected or redundant, even if a selected test
+// doesn't cover any outcome that no other test covers.
+func Test_redundantTests(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	tests := map[string]map[outcome]bool{
+		"TestAll":      {{0, true}: true, {0, false}: true},
+		"TestNegative": {{0, true}: true},
+		"TestPositive": {{0, false}: true},
+	}
+
+	selected, _ := minimalTests(tests)
+	redundant := redundantTests(tests, selected)
+
+	s.CheckEquals(selected, []string{"TestAll"})
+	s.CheckEquals(redundant, []string{"TestNegative", "TestPositive"})
+}
+
+func Test_testOutcomes(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	conds := []condition{
+		{Start: "a.go:1:1", Code: "a", TrueCount: 2, FalseCount: 1, TrueTests: []string{"TestA/sub", "TestB"}, FalseTests: []string{"TestA"}},
+	}
+
+	s.CheckEquals(testOutcomes(conds), map[string]map[outcome]bool{
+		"TestA": {{0, true}: true, {0, false}: true},
+		"TestB": {{0, true}: true},
+	})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mutate checks whether the tests detect a change in the conditions.
+//
+// It builds the test binary once and then runs it once for each covered
+// condition, with that condition negated.
+// If the tests still pass, the mutant survives,
+// which means that the tests don't assert on the outcome of the condition.
+func (g *gobco) mutate() {
+	for _, arg := range g.args {
+		g.mutateArg(arg)
+	}
+}
+
+func (g *gobco) mutateArg(arg argInfo) {
+	gopaths := ""
+	if !arg.module {
+		gopaths = g.gopaths()
+	}
+	dir := g.file(arg.instrDir)
+
+	// The mutants are identified by the index of the condition
+	// in the test binary, so the counts must not be merged
+	// with conditions from an existing stats file.
+	statsFilename := g.file("gobco-unmutated.json")
+	_ = os.Remove(statsFilename)
+	env := goTest{}.env(g.tmpdir, gopaths, statsFilename)
+
+	buildArgs, runArgs := splitTestArgs(g.goTestArgs)
+	binary := g.file("gobco-mutate.test")
+	goTestCArgs := append([]string{"test", "-c", "-o", binary}, buildArgs...)
+	goTestC := exec.Command("go", append(goTestCArgs, ".")...)
+	goTestC.Stdout = g.stdout
+	goTestC.Stderr = g.stderr
+	goTestC.Dir = dir
+	goTestC.Env = env
+	g.verbosef("Running \"go test -c\" in %q", dir)
+	if err := goTestC.Run(); err != nil {
+		g.errf("go test -c %s: %s", arg.arg, err)
+		g.exitCode = 1
+		return
+	}
+
+	start := time.Now()
+	if err := g.runTestBinary(binary, dir, env, runArgs, 0); err != nil {
+		g.errf("the tests of %s must pass before mutating: %s", arg.arg, err)
+		g.exitCode = 1
+		return
+	}
+	// Negating a loop condition may result in an endless loop.
+	timeout := 10*time.Since(start) + 10*time.Second
+
+	conds, err := g.load(statsFilename)
+	g.check(err)
+
+	var candidates []int
+	for i, cond := range conds {
+		if cond.Kind == "" && cond.TrueCount+cond.FalseCount > 0 {
+			candidates = append(candidates, i)
+		}
+	}
+	candidates = sample(candidates, g.sample)
+
+	mutantStats := g.file("gobco-mutant.json")
+	var survived []condition
+	for _, idx := range candidates {
+		env := goTest{}.env(g.tmpdir, gopaths, mutantStats)
+		env = append(env, "GOBCO_MUTATE="+strconv.Itoa(idx))
+		err := g.runTestBinary(binary, dir, env, runArgs, timeout)
+		g.verbosef("Mutant %s: %v", conds[idx].Start, err)
+		if err == nil {
+			survived = append(survived, conds[idx])
+		}
+		_ = os.Remove(mutantStats)
+	}
+
+	g.outf("")
+	g.outf("Mutation score: %d/%d killed",
+		len(candidates)-len(survived), len(candidates))
+	for _, cond := range survived {
+		g.outf("%s: negating condition %q did not fail any test",
+			cond.Start, cond.Code)
+	}
+}
+
+// runTestBinary runs the compiled tests,
+// passing the given timeout to the testing package if it is nonzero.
+func (g *gobco) runTestBinary(binary, dir string, env, runArgs []string, timeout time.Duration) error {
+	args := append([]string{"-test.count=1"}, runArgs...)
+	if timeout > 0 {
+		args = append(args, "-test.timeout="+timeout.String())
+	}
+	cmd := exec.Command(binary, args...)
+	cmd.Dir = dir
+	cmd.Env = env
+	if g.verbose {
+		cmd.Stdout = g.stderr
+		cmd.Stderr = g.stderr
+	}
+	return cmd.Run()
+}
+
+// testBinaryFlags lists the flags of 'go test' that are passed
+// to the test binary, with whether they are boolean.
+var testBinaryFlags = map[string]bool{
+	"bench": false, "benchmem": true, "benchtime": false,
+	"blockprofile": false, "blockprofilerate": false,
+	"count": false, "coverprofile": false, "cpu": false, "cpuprofile": false,
+	"failfast": true, "fullpath": true, "list": false,
+	"memprofile": false, "memprofilerate": false,
+	"mutexprofile": false, "mutexprofilefraction": false,
+	"outputdir": false, "parallel": false, "run": false, "short": true,
+	"shuffle": false, "skip": false, "timeout": false, "trace": false,
+	"v": true,
+}
+
+// splitTestArgs splits the arguments from -test into the flags for building
+// the test binary using 'go test -c' and the flags for running it.
+// The test binary expects its flags with the prefix "test.",
+// and the arguments after "-args" unmodified.
+func splitTestArgs(args []string) (buildArgs, runArgs []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-args" || arg == "--args" {
+			runArgs = append(runArgs, args[i+1:]...)
+			break
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		value := ""
+		if eq := strings.IndexByte(name, '='); eq != -1 {
+			name, value = name[:eq], name[eq:]
+		}
+		name = strings.TrimPrefix(name, "test.")
+		isBool, isTestFlag := testBinaryFlags[name]
+		if !strings.HasPrefix(arg, "-") || !isTestFlag {
+			buildArgs = append(buildArgs, arg)
+			continue
+		}
+
+		runArgs = append(runArgs, "-test."+name+value)
+		if value == "" && !isBool && i+1 < len(args) {
+			i++
+			runArgs = append(runArgs, args[i])
+		}
+	}
+	return
+}
+
+// sample returns at most n of the elements, evenly distributed,
+// or all elements if n is 0.
+func sample(elements []int, n int) []int {
+	if n <= 0 || n >= len(elements) {
+		return elements
+	}
+	result := make([]int, n)
+	for i := range result {
+		result[i] = elements[i*len(elements)/n]
+	}
+	return result
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_sample(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	elements := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+	s.CheckEquals(sample(elements, 0), elements)
+	s.CheckEquals(sample(elements, 20), elements)
+	s.CheckEquals(sample(elements, 3), []int{0, 3, 6})
+	s.CheckEquals(sample(elements, 1), []int{0})
+}
+
+func Test_splitTestArgs(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	test := func(args []string, buildArgs, runArgs []string) {
+		actualBuild, actualRun := splitTestArgs(args)
+		s.CheckEquals(actualBuild, buildArgs)
+		s.CheckEquals(actualRun, runArgs)
+	}
+
+	test(nil, nil, nil)
+	test([]string{"-tags", "integration", "-vet=off"},
+		[]string{"-tags", "integration", "-vet=off"}, nil)
+	test([]string{"-run", "Test_isSmall", "-short", "-count=3"},
+		nil, []string{"-test.run", "Test_isSmall", "-test.short", "-test.count=3"})
+	test([]string{"-test.v", "-race", "-args", "-run", "x"},
+		[]string{"-race"}, []string{"-test.v", "-run", "x"})
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// report merges the stats files from the command line
+// and prints the combined coverage.
+//
+// The stats files typically come from a program built by 'gobco build',
+// which writes a separate file per process to the directory GOBCO_COVERDIR.
+func (g *gobco) report() {
+	conds, err := g.loadReportArgs()
+	if err != nil {
+		g.errf("%s", err)
+		g.exitCode = 1
+		return
+	}
+
+	if g.statsFilename != "" {
+		g.check(g.persist(g.statsFilename, conds))
+	}
+
+	if g.who != "" {
+		g.printWho(conds)
+		return
+	}
+	g.printReports(conds)
+}
+
+// reportFormats lists the formats for the -format option.
+var reportFormats = []string{"cobertura", "json", "lcov", "markdown", "sarif"}
+
+func isReportFormat(format string) bool {
+	for _, f := range reportFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// printReports prints the coverage of the conditions
+// and writes the reports that are requested by the command line options.
+// A report from -format without -o replaces the text output on stdout.
+func (g *gobco) printReports(conds []condition) {
+	if g.format == "" || g.output != "" {
+		if g.command == "show" || g.show != "" {
+			g.printSource(conds)
+		} else {
+			g.printConds(conds)
+		}
+	}
+
+	if g.html != "" {
+		if err := writeHTML(g.html, conds); err != nil {
+			g.errf("%s", err)
+			g.exitCode = 1
+		}
+	}
+
+	if g.format != "" {
+		if err := g.writeFormat(conds); err != nil {
+			g.errf("%s", err)
+			g.exitCode = 1
+		}
+	}
+
+	if g.baseline != "" {
+		g.checkBaseline(conds)
+	}
+}
+
+// writeFormat writes the report from -format to the file from -o,
+// or to stdout.
+func (g *gobco) writeFormat(conds []condition) (err error) {
+	var out io.Writer = g.stdout
+	if g.output != "" {
+		file, err := os.Create(g.output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			closeErr := file.Close()
+			if err == nil {
+				err = closeErr
+			}
+		}()
+		out = file
+	}
+
+	w := bufio.NewWriter(out)
+	switch g.format {
+	case "cobertura":
+		err = writeCobertura(w, conds, time.Now())
+	case "json":
+		err = writeJSONReport(w, conds, g.branch, time.Now())
+	case "lcov":
+		err = writeLCOV(w, conds)
+	case "markdown":
+		var previous []condition
+		if g.compare != "" {
+			previous, err = g.load(g.compare)
+			if err != nil {
+				return err
+			}
+		}
+		err = g.writeMarkdown(w, conds, previous)
+	case "sarif":
+		err = writeSARIF(w, conds)
+	}
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// printWho lists the tests that cover the conditions at the location
+// from the -who option.
+// The location may omit leading directories, as in "file.go:12:5".
+func (g *gobco) printWho(conds []condition) {
+	who := filepath.ToSlash(g.who)
+
+	found := false
+	for _, cond := range conds {
+		start := filepath.ToSlash(cond.Start)
+		if start != who && !strings.HasSuffix(start, "/"+who) {
+			continue
+		}
+		found = true
+
+		if cond.Kind == "" {
+			g.outf("%s: condition %q", cond.Start, cond.Code)
+			g.outf("  true: %s", testNames(cond.TrueTests))
+			g.outf("  false: %s", testNames(cond.FalseTests))
+		} else {
+			g.outf("%s: %s %q", cond.Start, cond.Kind, cond.Code)
+			g.outf("  covered: %s", testNames(cond.TrueTests))
+		}
+	}
+
+	if !found {
+		g.errf("no condition at %s", g.who)
+		g.exitCode = 1
+	}
+}
+
+func testNames(tests []string) string {
+	if len(tests) == 0 {
+		return "no test"
+	}
+	return strings.Join(tests, ", ")
+}
+
+// loadReportArgs loads and merges the stats files from the command line.
+func (g *gobco) loadReportArgs() ([]condition, error) {
+	return g.loadStats(g.reportArgs)
+}
+
+// loadStats loads and merges the stats files from the arguments.
+func (g *gobco) loadStats(args []string) ([]condition, error) {
+	filenames, err := g.statsFiles(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var conds []condition
+	for _, filename := range filenames {
+		fileConds, err := g.loadWithCounts(filename)
+		if err != nil {
+			return nil, err
+		}
+		conds = mergeConds(conds, fileConds)
+	}
+	return conds, nil
+}
+
+// statsFiles returns the stats files from the arguments,
+// which are either files or directories containing *.json files.
+func (g *gobco) statsFiles(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no stats files given")
+	}
+
+	var filenames []string
+	for _, arg := range args {
+		st, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !st.IsDir() {
+			filenames = append(filenames, arg)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(arg, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no stats files found in %s", arg)
+		}
+		filenames = append(filenames, matches...)
+	}
+	return filenames, nil
+}
+
+// loadWithCounts loads the stats file and adds the counts files
+// that a process in immediate mode has left behind.
+func (g *gobco) loadWithCounts(filename string) ([]condition, error) {
+	conds, err := g.load(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	countsFilenames, err := filepath.Glob(filename + ".*.counts")
+	if err != nil {
+		return nil, err
+	}
+	for _, countsFilename := range countsFilenames {
+		counts, err := os.ReadFile(countsFilename)
+		if err == nil {
+			conds, err = addCounts(conds, counts)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", countsFilename, err)
+		}
+	}
+	return conds, nil
+}
+
+// mergeConds adds the counts from src to the corresponding conditions
+// in dst, appending the conditions that are not yet in dst.
+// The conditions are matched by their location and code.
+func mergeConds(dst, src []condition) []condition {
+	type key struct {
+		start string
+		code  string
+		n     int // to distinguish conditions with the same location and code
+	}
+
+	keys := func(conds []condition) []key {
+		seen := map[key]int{}
+		var keys []key
+		for _, cond := range conds {
+			k := key{cond.Start, cond.Code, 0}
+			k.n = seen[k]
+			seen[k]++
+			keys = append(keys, k)
+		}
+		return keys
+	}
+
+	index := map[key]int{}
+	for i, k := range keys(dst) {
+		index[k] = i
+	}
+
+	for i, k := range keys(src) {
+		if j, ok := index[k]; ok {
+			dst[j].TrueCount += src[i].TrueCount
+			dst[j].FalseCount += src[i].FalseCount
+			dst[j].TrueTests = union(dst[j].TrueTests, src[i].TrueTests)
+			dst[j].FalseTests = union(dst[j].FalseTests, src[i].FalseTests)
+			dst[j].ForcedTrueCount += src[i].ForcedTrueCount
+			dst[j].ForcedFalseCount += src[i].ForcedFalseCount
+		} else {
+			dst = append(dst, src[i])
+		}
+	}
+	return dst
+}
+
+// union returns the sorted names that occur in a or b.
+func union(a, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+	set := map[string]bool{}
+	for _, name := range a {
+		set[name] = true
+	}
+	for _, name := range b {
+		set[name] = true
+	}
+	var names []string
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// position is a location in a source file, as in "file.go:12:5".
+type position struct {
+	filename string
+	line     int
+	col      int
+}
+
+// parsePosition parses a location of the form "file.go:12:5".
+// The filename may itself contain colons, as in "C:\file.go:12:5".
+func parsePosition(pos string) (position, bool) {
+	colSep := strings.LastIndexByte(pos, ':')
+	if colSep < 0 {
+		return position{}, false
+	}
+	lineSep := strings.LastIndexByte(pos[:colSep], ':')
+	if lineSep < 0 {
+		return position{}, false
+	}
+	line, err1 := strconv.Atoi(pos[lineSep+1 : colSep])
+	col, err2 := strconv.Atoi(pos[colSep+1:])
+	if err1 != nil || err2 != nil || line < 1 || col < 1 {
+		return position{}, false
+	}
+	return position{pos[:lineSep], line, col}, true
+}
+
+// fileConds are the conditions from a single source file.
+type fileConds struct {
+	name  string
+	conds []condition
+}
+
+func (f fileConds) outcomes() (covered, total int) {
+	for _, cond := range f.conds {
+		cov, outcomes := cond.outcomes()
+		covered += cov
+		total += outcomes
+	}
+	return
+}
+
+// condsByFile groups the conditions by their file,
+// sorted by filename.
+// Conditions without a valid location are skipped.
+func condsByFile(conds []condition) []fileConds {
+	byName := map[string][]condition{}
+	for _, cond := range conds {
+		if pos, ok := parsePosition(cond.Start); ok {
+			byName[pos.filename] = append(byName[pos.filename], cond)
+		}
+	}
+
+	var files []fileConds
+	for name, conds := range byName {
+		files = append(files, fileConds{name, conds})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].name < files[j].name
+	})
+	return files
+}
+
+// percentage formats the ratio of covered to total outcomes,
+// such as "75.0%".
+func percentage(covered, total int) string {
+	if total == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(covered)/float64(total))
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_mergeConds(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	dst := []condition{
+		{Start: "a.go:1:1", Code: "a", TrueCount: 1, FalseCount: 0},
+		{Start: "a.go:2:1", Code: "b", TrueCount: 0, FalseCount: 1},
+		{Start: "a.go:2:1", Code: "b", TrueCount: 0, FalseCount: 2},
+	}
+	src := []condition{
+		{Start: "a.go:2:1", Code: "b", TrueCount: 3, FalseCount: 0},
+		{Start: "a.go:2:1", Code: "b", TrueCount: 4, FalseCount: 0},
+		{Start: "a.go:3:1", Code: "c", TrueCount: 5, FalseCount: 5},
+	}
+
+	s.CheckEquals(mergeConds(dst, src), []condition{
+		{Start: "a.go:1:1", Code: "a", TrueCount: 1, FalseCount: 0},
+		{Start: "a.go:2:1", Code: "b", TrueCount: 3, FalseCount: 1},
+		{Start: "a.go:2:1", Code: "b", TrueCount: 4, FalseCount: 2},
+		{Start: "a.go:3:1", Code: "c", TrueCount: 5, FalseCount: 5},
+	})
+}
+
+func Test_mergeConds__tests(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	dst := []condition{{Start: "a.go:1:1", Code: "a", TrueCount: 1, FalseCount: 1, TrueTests: []string{"TestB"}}}
+	src := []condition{{Start: "a.go:1:1", Code: "a", TrueCount: 1, FalseCount: 1, TrueTests: []string{"TestA", "TestB"}, FalseTests: []string{"TestC"}}}
+
+	s.CheckEquals(mergeConds(dst, src), []condition{
+		{Start: "a.go:1:1", Code: "a", TrueCount: 2, FalseCount: 2, TrueTests: []string{"TestA", "TestB"}, FalseTests: []string{"TestC"}},
+	})
+}
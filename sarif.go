@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// The SARIF 2.1.0 format, as described in
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+// Only the parts that gobco needs are modeled here.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties sarifProperties `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+type sarifProperties struct {
+	Code       string `json:"code"`
+	Kind       string `json:"kind,omitempty"`
+	Class      string `json:"class,omitempty"`
+	TrueCount  int    `json:"trueCount"`
+	FalseCount int    `json:"falseCount"`
+}
+
+var sarifRules = []sarifRule{
+	{"never-evaluated", sarifMessage{"The condition was never evaluated."}},
+	{"never-true", sarifMessage{"The condition was never true."}},
+	{"never-false", sarifMessage{"The condition was never false."}},
+}
+
+// writeSARIF writes the uncovered and partially covered conditions
+// as SARIF results, for code scanning tools.
+func writeSARIF(w io.Writer, conds []condition) error {
+	run := sarifRun{
+		Tool: sarifTool{sarifDriver{
+			"gobco",
+			version,
+			"https://github.com/rillig/gobco",
+			sarifRules,
+		}},
+		Results: []sarifResult{},
+	}
+
+	for _, cond := range conds {
+		ruleID, message := sarifFinding(cond)
+		if ruleID == "" {
+			continue
+		}
+		start, ok := parsePosition(cond.Start)
+		if !ok {
+			continue
+		}
+
+		region := sarifRegion{StartLine: start.line, StartColumn: start.col}
+		if end, ok := parsePosition(cond.End); ok {
+			region.EndLine = end.line
+			region.EndColumn = end.col
+		}
+
+		level := "note"
+		if ruleID == "never-evaluated" {
+			level = "warning"
+		}
+
+		run.Results = append(run.Results, sarifResult{
+			ruleID,
+			level,
+			sarifMessage{message},
+			[]sarifLocation{{sarifPhysicalLocation{
+				sarifArtifactLocation{filepath.ToSlash(start.filename)},
+				region,
+			}}},
+			sarifProperties{
+				cond.Code,
+				cond.Kind,
+				cond.Class,
+				cond.TrueCount,
+				cond.FalseCount,
+			},
+		})
+	}
+
+	doc := sarifLog{
+		"https://json.schemastore.org/sarif-2.1.0.json",
+		"2.1.0",
+		[]sarifRun{run},
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(doc)
+}
+
+// sarifFinding returns the rule and the message for a condition
+// that is not fully covered, or "" for a fully covered condition.
+func sarifFinding(cond condition) (ruleID, message string) {
+	switch cond.Kind {
+	case "enum":
+		if cond.TrueCount > 0 {
+			return "", ""
+		}
+		subject, value := cond.enumValue()
+		return "never-true", fmt.Sprintf("%s never saw %s", subject, value)
+	case "return", "panic":
+		if cond.TrueCount > 0 {
+			return "", ""
+		}
+		return "never-evaluated", fmt.Sprintf("%s was never executed", cond.Kind)
+	}
+
+	switch cond.state() {
+	case "never":
+		return "never-evaluated", fmt.Sprintf("condition %q was never evaluated", cond.Code)
+	case "false-only":
+		return "never-true", fmt.Sprintf("condition %q was never true", cond.Code)
+	case "true-only":
+		return "never-false", fmt.Sprintf("condition %q was never false", cond.Code)
+	}
+	return "", ""
+}
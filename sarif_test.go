@@ -0,0 +1,132 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_writeSARIF(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	conds := []condition{
+		{Start: "pkg/a.go:3:5", Code: "a", TrueCount: 2, FalseCount: 1, End: "pkg/a.go:3:6"},
+		{Start: "pkg/a.go:3:10", Code: "b < 0", TrueCount: 2, FalseCount: 0, Class: "const", End: "pkg/a.go:3:15"},
+		{Start: "pkg/b.go:4:2", Code: "return 0", TrueCount: 0, FalseCount: 0, Kind: "return", Class: "return"},
+	}
+
+	var sb strings.Builder
+	err := writeSARIF(&sb, conds)
+
+	s.CheckEquals(err, nil)
+	s.CheckEquals(sb.String(), ""+
+		"{\n"+
+		"  \"$schema\": \"https://json.schemastore.org/sarif-2.1.0.json\",\n"+
+		"  \"version\": \"2.1.0\",\n"+
+		"  \"runs\": [\n"+
+		"    {\n"+
+		"      \"tool\": {\n"+
+		"        \"driver\": {\n"+
+		"          \"name\": \"gobco\",\n"+
+		"          \"version\": \""+version+"\",\n"+
+		"          \"informationUri\": \"https://github.com/rillig/gobco\",\n"+
+		"          \"rules\": [\n"+
+		"            {\n"+
+		"              \"id\": \"never-evaluated\",\n"+
+		"              \"shortDescription\": {\n"+
+		"                \"text\": \"The condition was never evaluated.\"\n"+
+		"              }\n"+
+		"            },\n"+
+		"            {\n"+
+		"              \"id\": \"never-true\",\n"+
+		"              \"shortDescription\": {\n"+
+		"                \"text\": \"The condition was never true.\"\n"+
+		"              }\n"+
+		"            },\n"+
+		"            {\n"+
+		"              \"id\": \"never-false\",\n"+
+		"              \"shortDescription\": {\n"+
+		"                \"text\": \"The condition was never false.\"\n"+
+		"              }\n"+
+		"            }\n"+
+		"          ]\n"+
+		"        }\n"+
+		"      },\n"+
+		"      \"results\": [\n"+
+		"        {\n"+
+		"          \"ruleId\": \"never-false\",\n"+
+		"          \"level\": \"note\",\n"+
+		"          \"message\": {\n"+
+		"            \"text\": \"condition \\\"b < 0\\\" was never false\"\n"+
+		"          },\n"+
+		"          \"locations\": [\n"+
+		"            {\n"+
+		"              \"physicalLocation\": {\n"+
+		"                \"artifactLocation\": {\n"+
+		"                  \"uri\": \"pkg/a.go\"\n"+
+		"                },\n"+
+		"                \"region\": {\n"+
+		"                  \"startLine\": 3,\n"+
+		"                  \"startColumn\": 10,\n"+
+		"                  \"endLine\": 3,\n"+
+		"                  \"endColumn\": 15\n"+
+		"                }\n"+
+		"              }\n"+
+		"            }\n"+
+		"          ],\n"+
+		"          \"properties\": {\n"+
+		"            \"code\": \"b < 0\",\n"+
+		"            \"class\": \"const\",\n"+
+		"            \"trueCount\": 2,\n"+
+		"            \"falseCount\": 0\n"+
+		"          }\n"+
+		"        },\n"+
+		"        {\n"+
+		"          \"ruleId\": \"never-evaluated\",\n"+
+		"          \"level\": \"warning\",\n"+
+		"          \"message\": {\n"+
+		"            \"text\": \"return was never executed\"\n"+
+		"          },\n"+
+		"          \"locations\": [\n"+
+		"            {\n"+
+		"              \"physicalLocation\": {\n"+
+		"                \"artifactLocation\": {\n"+
+		"                  \"uri\": \"pkg/b.go\"\n"+
+		"                },\n"+
+		"                \"region\": {\n"+
+		"                  \"startLine\": 4,\n"+
+		"                  \"startColumn\": 2\n"+
+		"                }\n"+
+		"              }\n"+
+		"            }\n"+
+		"          ],\n"+
+		"          \"properties\": {\n"+
+		"            \"code\": \"return 0\",\n"+
+		"            \"kind\": \"return\",\n"+
+		"            \"class\": \"return\",\n"+
+		"            \"trueCount\": 0,\n"+
+		"            \"falseCount\": 0\n"+
+		"          }\n"+
+		"        }\n"+
+		"      ]\n"+
+		"    }\n"+
+		"  ]\n"+
+		"}\n")
+}
+
+func Test_sarifFinding__enum(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	test := func(code string, expected string) {
+		cond := condition{Start: "a.go:3:9", Code: code, Kind: "enum", Type: "Kind"}
+		ruleID, message := sarifFinding(cond)
+		s.CheckEquals(ruleID, "never-true")
+		s.CheckEquals(message, expected)
+	}
+
+	test("kind == KindDir", "switch on \"kind\" never saw Kind value KindDir")
+
+	// Stats files that have been edited by hand may contain any code.
+	test("KindDir", "switch never saw Kind value KindDir")
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// printSource prints the source code of the files with conditions,
+// or only of the function from the -show option,
+// with the coverage of each condition marked below the condition,
+// similar to the diagnostics of a compiler.
+func (g *gobco) printSource(conds []condition) {
+	g.printTotals(conds)
+
+	color := g.useColor()
+	found := false
+	for _, file := range condsByFile(conds) {
+		src, err := os.ReadFile(file.name)
+		if err != nil {
+			g.errf("%s", err)
+			g.exitCode = 1
+			continue
+		}
+
+		lines := strings.SplitAfter(string(src), "\n")
+		from, to := 1, len(lines)
+		if g.show != "" {
+			from, to = funcLines(file.name, src, g.show)
+			if from == 0 {
+				continue
+			}
+		}
+		found = true
+
+		g.outf("")
+		g.outf("%s:", filepath.ToSlash(file.name))
+		g.printLines(lines, from, to, file.conds, color)
+	}
+
+	if g.show != "" && !found {
+		g.errf("function %s not found", g.show)
+		g.exitCode = 1
+	}
+}
+
+// printLines prints the lines from..to, both inclusive and 1-based,
+// each followed by the markers for its conditions.
+func (g *gobco) printLines(lines []string, from, to int, conds []condition, color bool) {
+	byLine := map[int][]condition{}
+	for _, cond := range conds {
+		pos, _ := parsePosition(cond.Start)
+		byLine[pos.line] = append(byLine[pos.line], cond)
+	}
+
+	width := len(strconv.Itoa(to))
+	for lineno := from; lineno <= to && lineno <= len(lines); lineno++ {
+		line := strings.TrimRight(lines[lineno-1], "\r\n")
+		if lineno == len(lines) && line == "" {
+			break // after the final newline
+		}
+		g.outf("%s", strings.TrimRight(fmt.Sprintf("%*d | %s", width, lineno, line), " "))
+
+		lineConds := byLine[lineno]
+		sort.SliceStable(lineConds, func(i, j int) bool {
+			a, _ := parsePosition(lineConds[i].Start)
+			b, _ := parsePosition(lineConds[j].Start)
+			return a.col < b.col
+		})
+		for _, cond := range lineConds {
+			g.outf("%*s | %s", width, "", markCond(line, lineno, cond, color))
+		}
+	}
+}
+
+// markCond returns the marker line for the condition,
+// which underlines the condition in the line above it with carets.
+func markCond(line string, lineno int, cond condition, color bool) string {
+	start, _ := parsePosition(cond.Start)
+	startOffset := start.col - 1
+	if startOffset > len(line) {
+		startOffset = len(line)
+	}
+
+	endOffset := len(line)
+	if end, ok := parsePosition(cond.End); ok && end.line == lineno {
+		endOffset = end.col - 1
+	} else if !ok {
+		endOffset = tokenEnd(line, startOffset)
+	}
+	if endOffset > len(line) {
+		endOffset = len(line)
+	}
+
+	// Keep the tabs, so that the carets line up with the code above.
+	var indent strings.Builder
+	for _, r := range line[:startOffset] {
+		if r == '\t' {
+			indent.WriteByte('\t')
+		} else {
+			indent.WriteByte(' ')
+		}
+	}
+
+	carets := utf8.RuneCountInString(line[startOffset:endOffset])
+	if carets < 1 {
+		carets = 1
+	}
+	marker := strings.Repeat("^", carets) + " " + cond.coverageText()
+
+	if color {
+		marker = stateColors[cond.state()] + marker + "\x1b[0m"
+	}
+	return indent.String() + marker
+}
+
+// stateColors are the ANSI terminal colors for the coverage states.
+var stateColors = map[string]string{
+	"never":      "\x1b[31m", // red
+	"true-only":  "\x1b[33m", // yellow
+	"false-only": "\x1b[33m", // yellow
+	"covered":    "\x1b[32m", // green
+}
+
+// coverageText describes the counts of the condition briefly,
+// for the marker below the condition.
+func (c condition) coverageText() string {
+	switch c.Kind {
+	case "enum":
+		if c.TrueCount == 0 {
+			return "value never seen"
+		}
+		return "value seen " + times(c.TrueCount)
+	case "return", "panic":
+		if c.TrueCount == 0 {
+			return "never executed"
+		}
+		return "executed " + times(c.TrueCount)
+	}
+
+	switch c.state() {
+	case "never":
+		return "never evaluated"
+	case "true-only":
+		return times(c.TrueCount) + " true, never false"
+	case "false-only":
+		return "never true, " + times(c.FalseCount) + " false"
+	}
+	return times(c.TrueCount) + " true, " + times(c.FalseCount) + " false"
+}
+
+// funcLines returns the first and last line of the function declaration
+// with the given name, such as "Func" or "Type.Method",
+// or 0 if the file doesn't declare the function.
+func funcLines(filename string, src []byte, name string) (from, to int) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return 0, 0
+	}
+
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && funcDeclName(fn) == name {
+			return fset.Position(fn.Pos()).Line, fset.Position(fn.End()).Line
+		}
+	}
+	return 0, 0
+}
+
+// useColor returns whether the output goes directly to a terminal
+// that may show colors.
+func (g *gobco) useColor() bool {
+	if g.stdout != os.Stdout || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_markCond(t *testing.T) {
+	s := NewSuite(t)
+	defer s.TearDownTest()
+
+	line := "\tif ä && b == 3 {"
+
+	test := func(cond condition, color bool, expected string) {
+		s.CheckEquals(markCond(line, 7, cond, color), expected)
+	}
+
+	test(
+		condition{Start: "a.go:7:5", Code: "ä", TrueCount: 3, FalseCount: 0, End: "a.go:7:7"},
+		false,
+		"\t   ^ 3 times true, never false")
+	test(
+		condition{Start: "a.go:7:11", Code: "b == 3", TrueCount: 1, FalseCount: 2, End: "a.go:7:17"},
+		true,
+		"\t        \x1b[32m^^^^^^ once true, 2 times false\x1b[0m")
+	test(
+		condition{Start: "a.go:7:11", Code: "b == 3", TrueCount: 0, FalseCount: 2},
+		false,
+		"\t        ^ never true, 2 times false")
+	test(
+		condition{Start: "a.go:7:2", Code: "a && b == 3", TrueCount: 0, FalseCount: 0, End: "a.go:9:2"},
+		true,
+		"\t\x1b[31m^^^^^^^^^^^^^^^^ never evaluated\x1b[0m")
+	test(
+		condition{Start: "a.go:7:2", Code: "return", TrueCount: 1, FalseCount: 0, Kind: "return"},
+		false,
+		"\t^^ executed once")
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// summaryRow is a line in the table from the -summary option.
+type summaryRow struct {
+	file     string
+	fn       string // only for -summary=func
+	covered  int
+	outcomes int
+}
+
+func (r summaryRow) uncovered() int { return r.outcomes - r.covered }
+
+// summaryRows aggregates the conditions per file or per function.
+func summaryRows(conds []condition, perFunc bool) []summaryRow {
+	var rows []summaryRow
+	for _, file := range condsByFile(conds) {
+		index := map[string]int{}
+		for _, cond := range file.conds {
+			fn := ""
+			if perFunc {
+				fn = cond.Func
+				if fn == "" {
+					fn = "(outside functions)"
+				}
+			}
+			i, ok := index[fn]
+			if !ok {
+				i = len(rows)
+				index[fn] = i
+				rows = append(rows, summaryRow{file: filepath.ToSlash(file.name), fn: fn})
+			}
+			covered, outcomes := cond.outcomes()
+			rows[i].covered += covered
+			rows[i].outcomes += outcomes
+		}
+	}
+	return rows
+}
+
+// sortSummaryRows sorts the rows so that the rows that need the most
+// attention come first, either by their coverage ratio
+// or by their number of uncovered outcomes.
+func sortSummaryRows(rows []summaryRow, byUncovered bool) {
+	byRatio := func(a, b summaryRow) int {
+		// Compare a.covered/a.outcomes to b.covered/b.outcomes
+		// without rounding errors.
+		return a.covered*b.outcomes - b.covered*a.outcomes
+	}
+	byCount := func(a, b summaryRow) int {
+		return b.uncovered() - a.uncovered()
+	}
+
+	first, second := byRatio, byCount
+	if byUncovered {
+		first, second = byCount, byRatio
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if c := first(a, b); c != 0 {
+			return c < 0
+		}
+		if c := second(a, b); c != 0 {
+			return c < 0
+		}
+		if a.file != b.file {
+			return a.file < b.file
+		}
+		return a.fn < b.fn
+	})
+}
+
+// printSummary prints a table with the coverage per file or per function.
+func (g *gobco) printSummary(conds []condition) {
+	perFunc := g.summary == "func"
+	rows := summaryRows(conds, perFunc)
+	sortSummaryRows(rows, g.summarySort == "uncovered")
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 8, 2, ' ', 0)
+	if perFunc {
+		_, _ = fmt.Fprintf(w, "File\tFunction\tCovered\tCoverage\tUncovered\n")
+	} else {
+		_, _ = fmt.Fprintf(w, "File\tCovered\tCoverage\tUncovered\n")
+	}
+	for _, row := range rows {
+		name := row.file
+		if perFunc {
+			name += "\t" + row.fn
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%d/%d\t%s\t%d\n",
+			name, row.covered, row.outcomes,
+			percentage(row.covered, row.outcomes), row.uncovered())
+	}
+	_ = w.Flush()
+
+	g.outf("")
+	for _, line := range strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n") {
+		g.outf("%s", strings.TrimRight(line, " "))
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_gobco_printSummary(t *testing.T) {
+	conds := []condition{
+		{Start: "a.go:1:1", Code: "a", TrueCount: 1, FalseCount: 1, Func: "F"},
+		{Start: "a.go:2:1", Code: "b", TrueCount: 1, FalseCount: 0, Func: "F"},
+		{Start: "a.go:3:1", Code: "c", TrueCount: 0, FalseCount: 0, Func: "G"},
+		{Start: "a.go:4:1", Code: "c", TrueCount: 1, FalseCount: 1, Func: "F"},
+		{Start: "a.go:5:1", Code: "c", TrueCount: 0, FalseCount: 0, Func: "F"},
+		{Start: "a.go:6:1", Code: "c", TrueCount: 0, FalseCount: 0, Func: "F"},
+		{Start: "b.go:1:1", Code: "d", TrueCount: 0, FalseCount: 1},
+		{Start: "b.go:2:1", Code: "e", TrueCount: 0, FalseCount: 0, Func: "H"},
+		{Start: "b.go:3:1", Code: "f", TrueCount: 0, FalseCount: 0, Func: "H"},
+	}
+
+	test := func(summary, summarySort string, expected ...string) {
+		s := NewSuite(t)
+		defer s.TearDownTest()
+
+		g := s.newGobco()
+		g.summary = summary
+		g.summarySort = summarySort
+		g.printSummary(conds)
+
+		s.CheckEquals(s.Stdout(), "\n"+strings.Join(expected, "\n")+"\n")
+	}
+
+	test("file", "percent",
+		"File  Covered  Coverage  Uncovered",
+		"b.go  1/6      16.7%     5",
+		"a.go  5/12     41.7%     7")
+
+	test("file", "uncovered",
+		"File  Covered  Coverage  Uncovered",
+		"a.go  5/12     41.7%     7",
+		"b.go  1/6      16.7%     5")
+
+	test("func", "percent",
+		"File  Function             Covered  Coverage  Uncovered",
+		"b.go  H                    0/4      0.0%      4",
+		"a.go  G                    0/2      0.0%      2",
+		"a.go  F                    5/10     50.0%     5",
+		"b.go  (outside functions)  1/2      50.0%     1")
+
+	test("func", "uncovered",
+		"File  Function             Covered  Coverage  Uncovered",
+		"a.go  F                    5/10     50.0%     5",
+		"b.go  H                    0/4      0.0%      4",
+		"a.go  G                    0/2      0.0%      2",
+		"b.go  (outside functions)  1/2      50.0%     1")
+}
@@ -8,18 +8,96 @@ package main
 
 import (
 	"bufio"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 type gobcoOptions struct {
 	immediately bool
 	listAll     bool
+	recordTests bool
+	signals     bool
+	program     bool
 }
 
 type gobcoStats struct {
 	conds []gobcoCond
+
+	// The counters for each condition, first the true count,
+	// then the false count.
+	// They are kept separate from conds
+	// to guarantee the 64-bit alignment that is needed
+	// for the atomic operations on 32-bit platforms.
+	counts []int64
+
+	// Serializes writing the stats file and the counts file.
+	mu sync.Mutex
+
+	// In immediate mode, the counters are additionally kept
+	// in a binary file of fixed layout,
+	// which is much faster than writing the whole stats file
+	// after each increment.
+	// Gobco merges the counts file into the stats file afterwards.
+	//
+	// The file starts with the 8-byte magic "gobcocnt",
+	// followed by the number of counters as a uint64,
+	// followed by the counters as int64, all in little endian,
+	// followed by the location and code of each condition
+	// as a JSON array of pairs,
+	// as the stats file may also contain conditions from other packages.
+	// The counters only include the counts
+	// that have not been merged into the stats file yet.
+	//
+	// If possible, the counters are mapped into memory,
+	// so that each increment directly updates the file,
+	// which survives even if the process is killed.
+	// Otherwise, the counters are written to the file
+	// periodically and whenever the stats file is written.
+	countsFile *os.File
+	mapped     bool
+	dirty      uint32
+
+	// The counters as they were when they were last merged
+	// into the stats file.
+	flushed []int64
+
+	// The name of the stats file, see filename.
+	// A private stats file is only written by this process,
+	// so it doesn't need to be locked.
+	nameOnce sync.Once
+	name     string
+	private  bool
+
+	// When recording the tests,
+	// the names of the tests that are currently running,
+	// and for each counter, the names of the tests that covered it.
+	// When tests run in parallel,
+	// a condition is attributed to all of them.
+	testsMu sync.Mutex
+	running map[string]int
+	tests   []map[string]bool
+
+	// For 'gobco mutate', 1 + the index of the condition
+	// whose value is negated, or 0.
+	mutate int
+
+	// With GOBCO_FORCE, for each condition the forced outcome,
+	// which is 0 for not forced, 1 for true and 2 for false.
+	// For each condition, the forced evaluations are counted separately,
+	// in the same layout as counts.
+	force  []int8
+	forced []int64
 }
 
 type gobcoCond struct {
@@ -27,14 +105,40 @@ type gobcoCond struct {
 	Code       string
 	TrueCount  int
 	FalseCount int
+	Kind       string   `json:",omitempty"`
+	Class      string   `json:",omitempty"`
+	TrueTests  []string `json:",omitempty"`
+	FalseTests []string `json:",omitempty"`
+
+	ForcedTrueCount  int `json:",omitempty"`
+	ForcedFalseCount int `json:",omitempty"`
+
+	End  string `json:",omitempty"`
+	Func string `json:",omitempty"`
+	Type string `json:",omitempty"`
 }
 
+// filename returns the name of the stats file.
+//
+// When gobco runs the tests, it sets GOBCO_STATS.
+// A program from 'gobco build' instead writes a separate file per process
+// to the directory from GOBCO_COVERDIR.
+// If neither is set, the counts are not persisted,
+// and a program warns about this, as it is typically run by hand.
 func (st *gobcoStats) filename() string {
-	filename := os.Getenv("GOBCO_STATS")
-	if filename == "" {
-		panic("gobco: GOBCO_STATS environment variable must be set")
-	}
-	return filename
+	st.nameOnce.Do(func() {
+		if filename := os.Getenv("GOBCO_STATS"); filename != "" {
+			st.name = filename
+		} else if dir := os.Getenv("GOBCO_COVERDIR"); dir != "" {
+			base := fmt.Sprintf("gobco.%d.%d.json", os.Getpid(), time.Now().UnixNano())
+			st.name = filepath.Join(dir, base)
+			st.private = true
+		} else if gobcoOpts.program {
+			_, _ = fmt.Fprintln(os.Stderr,
+				"gobco: warning: GOBCO_COVERDIR is not set, no coverage data emitted")
+		}
+	})
+	return st.name
 }
 
 func (st *gobcoStats) check(err error) {
@@ -43,78 +147,429 @@ func (st *gobcoStats) check(err error) {
 	}
 }
 
-func (st *gobcoStats) load(filename string) {
+// load reads the conditions from the stats file.
+// It returns nil if the file does not exist or is corrupt.
+func (st *gobcoStats) load(filename string) []gobcoCond {
 	file, err := os.Open(filename)
 	if err != nil && os.IsNotExist(err) {
-		return
+		return nil
 	}
+	st.check(err)
 
 	defer func() { st.check(file.Close()) }()
 
 	var data []gobcoCond
 	decoder := json.NewDecoder(bufio.NewReader(file))
 	decoder.DisallowUnknownFields()
-	st.check(decoder.Decode(&data))
+	if err := decoder.Decode(&data); err != nil {
+		// The file may have been left behind by an older version
+		// or by an interrupted process.
+		_, _ = fmt.Fprintf(os.Stderr,
+			"gobco: ignoring the corrupt or incomplete stats file '%s': %s\n",
+			filename, err)
+		return nil
+	}
+	return data
+}
+
+// unflushed returns the conditions with the counts
+// that have not been merged into the stats file yet,
+// together with the current values of all counters,
+// followed by the forced counters.
+func (st *gobcoStats) unflushed() ([]gobcoCond, []int64) {
+	n := len(st.counts)
+	if st.flushed == nil {
+		st.flushed = make([]int64, n+len(st.forced))
+	}
+
+	current := make([]int64, n+len(st.forced))
+	for i := range st.counts {
+		current[i] = atomic.LoadInt64(&st.counts[i])
+	}
+	for i := range st.forced {
+		current[n+i] = atomic.LoadInt64(&st.forced[i])
+	}
+	delta := func(i int) int {
+		return int(current[i] - st.flushed[i])
+	}
 
-	if len(st.conds) != len(data) {
-		msg := fmt.Sprintf(
-			"gobco: stats file '%s' must have exactly %d coverage counters",
-			filename, len(st.conds))
-		panic(msg)
+	conds := make([]gobcoCond, len(st.conds))
+	copy(conds, st.conds)
+	for i := range conds {
+		conds[i].TrueCount = delta(2 * i)
+		conds[i].FalseCount = delta(2*i + 1)
+		if st.forced != nil {
+			conds[i].ForcedTrueCount = delta(n + 2*i)
+			conds[i].ForcedFalseCount = delta(n + 2*i + 1)
+		}
 	}
-	st.conds = data
+
+	st.testsMu.Lock()
+	defer st.testsMu.Unlock()
+	if st.tests != nil {
+		for i := range conds {
+			conds[i].TrueTests = gobcoSortedNames(st.tests[2*i])
+			conds[i].FalseTests = gobcoSortedNames(st.tests[2*i+1])
+		}
+	}
+
+	return conds, current
 }
 
-func (st *gobcoStats) merge(other *gobcoStats) {
+// merge adds the counts from other to the corresponding conditions,
+// appending the conditions that only appear in other,
+// such as those from another package that shares the stats file.
+// The conditions are matched by their location and code,
+// so that stats files from a slightly different instrumentation
+// don't mix up the counts.
+func (st *gobcoStats) merge(conds []gobcoCond, other []gobcoCond) []gobcoCond {
 	type key struct {
 		start string
 		code  string
+		n     int // to distinguish conditions with the same location and code
 	}
 
-	m := make(map[key]*gobcoCond)
-	for i, cond := range st.conds {
-		m[key{cond.Start, cond.Code}] = &st.conds[i]
+	seen := make(map[key]int)
+	keyOf := func(cond gobcoCond) key {
+		k := key{cond.Start, cond.Code, 0}
+		k.n = seen[k]
+		seen[k]++
+		return k
+	}
+
+	index := make(map[key]int)
+	for i, cond := range conds {
+		index[keyOf(cond)] = i
+	}
+
+	seen = make(map[key]int)
+	for _, datum := range other {
+		i, ok := index[keyOf(datum)]
+		if !ok {
+			conds = append(conds, datum)
+			continue
+		}
+		conds[i].TrueCount += datum.TrueCount
+		conds[i].FalseCount += datum.FalseCount
+		conds[i].TrueTests = gobcoUnion(conds[i].TrueTests, datum.TrueTests)
+		conds[i].FalseTests = gobcoUnion(conds[i].FalseTests, datum.FalseTests)
+		conds[i].ForcedTrueCount += datum.ForcedTrueCount
+		conds[i].ForcedFalseCount += datum.ForcedFalseCount
+	}
+	return conds
+}
+
+// startTest records that the test is running,
+// until the returned function is called.
+func (st *gobcoStats) startTest(name string) func() {
+	st.testsMu.Lock()
+	defer st.testsMu.Unlock()
+
+	if st.running == nil {
+		st.running = make(map[string]int)
 	}
+	st.running[name]++
+
+	return func() {
+		st.testsMu.Lock()
+		defer st.testsMu.Unlock()
 
-	for i := range other.conds {
-		datum := &other.conds[i]
-		cond := m[key{datum.Start, datum.Code}]
-		datum.TrueCount += cond.TrueCount
-		datum.FalseCount += cond.FalseCount
+		st.running[name]--
+		if st.running[name] == 0 {
+			delete(st.running, name)
+		}
 	}
 }
 
+// recordTests attributes the counter to the tests that are running.
+func (st *gobcoStats) recordTests(counter int) {
+	st.testsMu.Lock()
+	defer st.testsMu.Unlock()
+
+	if st.tests == nil {
+		st.tests = make([]map[string]bool, len(st.counts))
+	}
+	for name := range st.running {
+		if st.tests[counter] == nil {
+			st.tests[counter] = make(map[string]bool)
+		}
+		st.tests[counter][name] = true
+	}
+}
+
+func gobcoSortedNames(names map[string]bool) []string {
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func gobcoUnion(a, b []string) []string {
+	names := make(map[string]bool)
+	for _, name := range a {
+		names[name] = true
+	}
+	for _, name := range b {
+		names[name] = true
+	}
+	return gobcoSortedNames(names)
+}
+
+// createTemp creates a new file next to filename,
+// with the same permissions as from os.Create.
+func (st *gobcoStats) createTemp(filename string) *os.File {
+	for i := 0; ; i++ {
+		name := fmt.Sprintf("%s.%d.%d.tmp", filename, os.Getpid(), i)
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o666)
+		if !os.IsExist(err) {
+			st.check(err)
+			return file
+		}
+	}
+}
+
+// persist merges the counts that have accumulated since the previous call
+// into the stats file.
 func (st *gobcoStats) persist() {
-	// TODO: First write to a temporary file.
-	file, err := os.Create(st.filename())
-	st.check(err)
+	st.mu.Lock()
+	defer st.mu.Unlock()
 
-	defer func() { st.check(file.Close()) }()
+	filename := st.filename()
+	if filename == "" {
+		return
+	}
+	if !st.private {
+		unlock := st.lock(filename)
+		defer unlock()
+	}
+
+	conds, current := st.unflushed()
+	conds = st.merge(conds, st.load(filename))
+
+	// Write to a temporary file first and then rename it,
+	// so that the stats file is never left in a partial state,
+	// even if the process is killed while writing.
+	file := st.createTemp(filename)
+	renamed := false
+	defer func() {
+		if !renamed {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+		}
+	}()
 
 	buf := bufio.NewWriter(file)
 
 	encoder := json.NewEncoder(buf)
 	encoder.SetIndent("", "\t")
 	encoder.SetEscapeHTML(false)
-	st.check(encoder.Encode(st.conds))
+	st.check(encoder.Encode(conds))
 	st.check(buf.Flush())
+	st.check(file.Close())
+
+	st.check(os.Rename(file.Name(), filename))
+	renamed = true
+
+	for i := range current {
+		if i < len(st.counts) && st.mapped {
+			// The counters in the mapped counts file
+			// only include the counts that have not been merged.
+			atomic.AddInt64(&st.counts[i], st.flushed[i]-current[i])
+		} else {
+			st.flushed[i] = current[i]
+		}
+	}
+	if st.countsFile != nil && !st.mapped {
+		st.writeCounts()
+	}
 }
 
-func (st *gobcoStats) cover(idx int, cond bool) bool {
-	counts := &st.conds[idx]
-	if cond {
-		counts.TrueCount++
+// openCounts creates the counts file for immediate mode,
+// fills it with the current counters
+// and maps it into memory if possible.
+// It is called before the tests start,
+// as the counters must not be incremented while they are mapped.
+//
+// It also writes the stats file,
+// to provide the conditions that belong to the counters
+// in case the process terminates abnormally.
+//
+// Each process writes its own counts file,
+// as the process ID is part of the filename.
+func (st *gobcoStats) openCounts() {
+	st.persist()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.filename() == "" {
+		return
+	}
+	name := fmt.Sprintf("%s.%d.counts", st.filename(), os.Getpid())
+	flags := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+	file, err := os.OpenFile(name, flags, 0o666)
+	st.check(err)
+
+	st.countsFile = file
+	st.writeCounts()
+
+	keys := make([][2]string, len(st.conds))
+	for i, cond := range st.conds {
+		keys[i] = [2]string{cond.Start, cond.Code}
+	}
+	data, err := json.Marshal(keys)
+	st.check(err)
+	_, err = file.WriteAt(data, int64(16+8*len(st.counts)))
+	st.check(err)
+
+	if counts := st.mapCounts(file, len(st.counts)); counts != nil {
+		// The mapped counters start with the counts
+		// that have not been merged into the stats file yet.
+		for i := range counts {
+			st.flushed[i] = 0
+		}
+		st.counts = counts
+		st.mapped = true
 	} else {
-		counts.FalseCount++
+		go st.writeCountsPeriodically()
 	}
+}
 
-	if gobcoOpts.immediately {
-		st.persist()
+// writeCounts writes the counts that have not been merged into the stats
+// file yet to the counts file.
+func (st *gobcoStats) writeCounts() {
+	buf := make([]byte, 16+8*len(st.counts))
+	copy(buf, "gobcocnt")
+	binary.LittleEndian.PutUint64(buf[8:], uint64(len(st.counts)))
+	for i := range st.counts {
+		n := atomic.LoadInt64(&st.counts[i]) - st.flushed[i]
+		binary.LittleEndian.PutUint64(buf[16+8*i:], uint64(n))
+	}
+	_, err := st.countsFile.WriteAt(buf, 0)
+	st.check(err)
+}
+
+// writeCountsPeriodically writes the counters to the counts file
+// whenever they have changed,
+// for platforms on which the file cannot be mapped into memory.
+func (st *gobcoStats) writeCountsPeriodically() {
+	for range time.Tick(100 * time.Millisecond) {
+		if atomic.SwapUint32(&st.dirty, 0) != 0 {
+			st.mu.Lock()
+			st.writeCounts()
+			st.mu.Unlock()
+		}
+	}
+}
+
+func (st *gobcoStats) cover(idx int, cond bool) bool {
+	if st.force != nil && st.force[idx] != 0 {
+		forced := st.force[idx] == 1
+		counter := 2 * idx
+		if !forced {
+			counter++
+		}
+		atomic.AddInt64(&st.forced[counter], 1)
+		return forced
+	}
+
+	if idx+1 == st.mutate {
+		cond = !cond
+	}
+
+	counter := 2 * idx
+	if !cond {
+		counter++
+	}
+	atomic.AddInt64(&st.counts[counter], 1)
+
+	if gobcoOpts.recordTests {
+		st.recordTests(counter)
+	}
+	if gobcoOpts.immediately && !st.mapped {
+		atomic.StoreUint32(&st.dirty, 1)
 	}
 
 	return cond
 }
 
+// flushOnSignal persists the counts when the process is interrupted,
+// for example by pressing Ctrl+C or by 'kill'.
+// Afterwards, the signal is raised again,
+// to terminate the process as if gobco had not intercepted the signal.
+//
+// This is only done in test binaries that don't handle signals themselves,
+// as raising the signal again would deliver it twice to their handlers.
+// A program from 'gobco build' keeps the default signal handling.
+func (st *gobcoStats) flushOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-signals
+		st.persist()
+		signal.Stop(signals)
+
+		p, err := os.FindProcess(os.Getpid())
+		if err == nil && p.Signal(sig) == nil {
+			return
+		}
+		os.Exit(1)
+	}()
+}
+
+// parseForce parses the GOBCO_FORCE environment variable,
+// which has the form 'file.go:42:5=true,other.go:7:3=false'.
+// A location may omit the leading directories.
+func (st *gobcoStats) parseForce(spec string) {
+	st.force = make([]int8, len(st.conds))
+	st.forced = make([]int64, len(st.counts))
+
+	for _, item := range strings.Split(spec, ",") {
+		sep := strings.LastIndex(item, "=")
+		value, err := strconv.ParseBool(item[sep+1:])
+		if sep < 0 || err != nil {
+			_, _ = fmt.Fprintf(os.Stderr,
+				"gobco: ignoring invalid GOBCO_FORCE entry %q\n", item)
+			continue
+		}
+		loc := filepath.ToSlash(item[:sep])
+
+		found := false
+		for i, cond := range st.conds {
+			start := filepath.ToSlash(cond.Start)
+			if cond.Kind == "" && (start == loc || strings.HasSuffix(start, "/"+loc)) {
+				found = true
+				st.force[i] = 2
+				if value {
+					st.force[i] = 1
+				}
+			}
+		}
+		if !found {
+			_, _ = fmt.Fprintf(os.Stderr,
+				"gobco: GOBCO_FORCE: no condition at %s\n", loc)
+		}
+	}
+}
+
+func init() {
+	if spec := os.Getenv("GOBCO_FORCE"); spec != "" {
+		gobcoCounts.parseForce(spec)
+	}
+	if idx, err := strconv.Atoi(os.Getenv("GOBCO_MUTATE")); err == nil {
+		gobcoCounts.mutate = idx + 1
+	}
+	if gobcoOpts.immediately {
+		gobcoCounts.openCounts()
+	}
+	if gobcoOpts.signals {
+		gobcoCounts.flushOnSignal()
+	}
+}
+
 func (st *gobcoStats) finish(exitCode int) int {
 	st.persist()
 	return exitCode
@@ -126,6 +581,27 @@ func GobcoCover(idx int, cond bool) bool {
 	return gobcoCounts.cover(idx, cond)
 }
 
+// GobcoRecover is deferred at the beginning of each test function,
+// to persist the counts if the test panics,
+// as the panic then terminates the process.
+// Afterwards, the test panics again with the same value.
+func GobcoRecover() {
+	if r := recover(); r != nil {
+		gobcoCounts.persist()
+		panic(r)
+	}
+}
+
+// GobcoTest is called at the beginning of each test function,
+// to attribute the conditions to the tests that cover them.
+// The returned function must be called when the test finishes.
+//
+// The parameter is a *testing.T, which is not mentioned here
+// to avoid importing the package testing into non-test code.
+func GobcoTest(t interface{ Name() string }) func() {
+	return gobcoCounts.startTest(t.Name())
+}
+
 // GobcoFinish needs to be exported to black-box test packages.
 func GobcoFinish(code int) int {
 	return gobcoCounts.finish(code)
@@ -12,7 +12,6 @@ import (
 )
 
 func TestMain(m *testing.M) {
-	gobcoCounts.load(gobcoCounts.filename())
 	exitCode := m.Run()
 	gobcoCounts.persist()
 	os.Exit(exitCode)
@@ -0,0 +1,90 @@
+//go:build ignore
+// +build ignore
+
+// This is the part of the gobco code that is injected into the package
+// being checked on operating systems that cannot map files into memory
+// or cannot lock files.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mapCounts returns nil, as the counts file cannot be mapped into memory.
+func (st *gobcoStats) mapCounts(file *os.File, n int) []int64 {
+	return nil
+}
+
+// lock acquires an advisory lock on the stats file,
+// as several processes may merge their counts into the same file,
+// for example when a test runs its own binary as a helper process.
+//
+// The lock is a separate file that exists as long as the lock is held
+// and that contains the process ID of its owner.
+// A process that is killed while holding the lock leaves the file behind.
+// Such a stale lock is removed as soon as its owner is known to be gone,
+// or after waiting for 10 seconds if that cannot be determined,
+// so that only the first process after the killed one has to wait.
+func (st *gobcoStats) lock(filename string) (unlock func()) {
+	lockName := filename + ".lock"
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		file, err := os.OpenFile(lockName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o666)
+		if err == nil {
+			_, err = fmt.Fprintf(file, "%d\n", os.Getpid())
+			st.check(err)
+			st.check(file.Close())
+			return func() { st.check(os.Remove(lockName)) }
+		}
+		if !os.IsExist(err) {
+			st.check(err)
+		}
+
+		timedOut := time.Now().After(deadline)
+		if timedOut || st.lockOwnerGone(lockName) {
+			if timedOut {
+				_, _ = fmt.Fprintf(os.Stderr,
+					"gobco: removing the stale lock '%s'\n", lockName)
+			}
+			err := os.Remove(lockName)
+			if err != nil && !os.IsNotExist(err) {
+				st.check(err)
+			}
+			deadline = time.Now().Add(10 * time.Second)
+			continue
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// lockOwnerGone returns whether the process that created the lock file
+// has terminated.
+// It returns false if that cannot be determined,
+// for example because the owner has not yet written its process ID.
+func (st *gobcoStats) lockOwnerGone(lockName string) bool {
+	content, err := os.ReadFile(lockName)
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return false
+	}
+
+	// On Windows, finding a process that has terminated fails.
+	if _, err := os.FindProcess(pid); err != nil {
+		return true
+	}
+
+	// On other systems, the processes may be listed in /proc.
+	if _, err := os.Stat("/proc"); err != nil {
+		return false
+	}
+	_, err = os.Stat(fmt.Sprintf("/proc/%d", pid))
+	return os.IsNotExist(err)
+}
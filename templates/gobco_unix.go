@@ -0,0 +1,62 @@
+//go:build ignore
+// +build ignore
+
+// This is the part of the gobco code that is injected into the package
+// being checked on operating systems that can map files into memory
+// and lock files.
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// mapCounts maps the n counters from the counts file into memory,
+// so that each increment of a counter directly updates the file.
+// It returns nil if the file cannot be mapped.
+func (st *gobcoStats) mapCounts(file *os.File, n int) []int64 {
+	// The counts file is little endian.
+	one := uint16(1)
+	if *(*byte)(unsafe.Pointer(&one)) != 1 || n == 0 {
+		return nil
+	}
+
+	prot := syscall.PROT_READ | syscall.PROT_WRITE
+	data, err := syscall.Mmap(int(file.Fd()), 0, 16+8*n, prot, syscall.MAP_SHARED)
+	if err != nil {
+		return nil
+	}
+
+	// Since the mapping starts at a page boundary,
+	// the counters are aligned for the atomic operations.
+	return (*[1 << 27]int64)(unsafe.Pointer(&data[16]))[:n:n]
+}
+
+// lock acquires an advisory lock on the stats file,
+// as several processes may merge their counts into the same file,
+// for example when a test runs its own binary as a helper process.
+//
+// The lock is held on a separate file,
+// as the stats file is replaced each time it is written.
+// The lock file is never removed, so that all processes lock the same file.
+// When a process terminates, even abnormally,
+// the operating system releases its lock.
+func (st *gobcoStats) lock(filename string) (unlock func()) {
+	file, err := os.OpenFile(filename+".lock", os.O_RDWR|os.O_CREATE, 0o666)
+	st.check(err)
+
+	for {
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	st.check(err)
+
+	return func() {
+		st.check(syscall.Flock(int(file.Fd()), syscall.LOCK_UN))
+		st.check(file.Close())
+	}
+}
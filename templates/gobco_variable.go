@@ -13,6 +13,7 @@ package main
 var gobcoOpts = gobcoOptions{
 	immediately: true,
 	listAll:     true,
+	recordTests: true,
 }
 
 var gobcoCounts = gobcoStats{
@@ -22,6 +23,9 @@ var gobcoCounts = gobcoStats{
 			Code:       "i > 0",
 			TrueCount:  0,
 			FalseCount: 0,
+			End:        "code.go:5:7",
+			Func:       "Func",
 		},
 	},
+	counts: make([]int64, 2),
 }
@@ -0,0 +1,5 @@
+package concurrent
+
+func isEven(i int) bool {
+	return i%2 == 0
+}
@@ -0,0 +1,22 @@
+package concurrent
+
+import (
+	"sync"
+	"testing"
+)
+
+// Test_isEven evaluates the condition from several goroutines at the same
+// time, to ensure that gobco doesn't lose any counts.
+func Test_isEven(t *testing.T) {
+	var wg sync.WaitGroup
+	for g := 0; g < 10; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10000; i++ {
+				isEven(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,5 @@
+package crash
+
+func isSmall(i int) bool {
+	return i < 10
+}
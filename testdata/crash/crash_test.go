@@ -0,0 +1,15 @@
+package crash
+
+import (
+	"os"
+	"testing"
+)
+
+// Test_crash terminates the test process before gobco has a chance
+// to persist the counts in the usual way.
+func Test_crash(t *testing.T) {
+	for i := 0; i < 15; i++ {
+		isSmall(i)
+	}
+	os.Exit(3)
+}
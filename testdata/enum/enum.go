@@ -0,0 +1,17 @@
+package enum
+
+type Kind int
+
+const (
+	KindFile Kind = iota
+	KindDir
+	KindArchive
+)
+
+func describe(kind Kind) string {
+	switch kind {
+	case KindFile:
+		return "file"
+	}
+	return "other"
+}
@@ -0,0 +1,12 @@
+package enum
+
+import "testing"
+
+func Test_describe(t *testing.T) {
+	if describe(KindFile) != "file" {
+		t.Error("file")
+	}
+	if describe(KindDir) != "other" {
+		t.Error("dir")
+	}
+}
@@ -0,0 +1,18 @@
+package exits
+
+func sign(x int) int {
+	switch {
+	case x < 0:
+		return -1
+	case x == 0:
+		panic("zero")
+	}
+	return 1
+}
+
+// shadowed calls a function that is named like the built-in panic,
+// which is not covered by -exits.
+func shadowed() {
+	panic := func(string) {}
+	panic("not the built-in")
+}
@@ -0,0 +1,9 @@
+package exits
+
+import "testing"
+
+func Test_sign(t *testing.T) {
+	if sign(5) != 1 {
+		t.Error("positive")
+	}
+}
@@ -0,0 +1,10 @@
+package force
+
+import "errors"
+
+func parse(s string) (int, error) {
+	if len(s) == 0 {
+		return 0, errors.New("empty")
+	}
+	return len(s), nil
+}
@@ -0,0 +1,14 @@
+package force
+
+import "testing"
+
+func Test_parse(t *testing.T) {
+	n, err := parse("abc")
+	if err != nil {
+		t.Logf("the error path: %v", err)
+		return
+	}
+	if n != 3 {
+		t.Errorf("expected 3, got %d", n)
+	}
+}
@@ -1,5 +1,7 @@
 package instrumenter
 
+import "net/url"
+
 // https://go.dev/ref/spec#Assignment_statements
 
 // assignStmt covers the instrumentation of [ast.AssignStmt], which has the
@@ -45,4 +47,46 @@ func assignStmt() {
 	// they are only defined on integer types.
 	i |= 7
 	i &= -7
+
+	// In the comma-ok forms, the 'ok' decides the later behavior of the
+	// code, even if it doesn't appear in a condition.
+	// Therefore, the outcome is recorded at the assignment itself.
+	counts := map[bool]int{}
+	v, found := counts[i > 0]
+	assertEquals(v, 0)
+	assertEquals(found, false)
+
+	var x interface{} = i
+	n, isInt := x.(int)
+	assertEquals(n, i)
+	assertEquals(isInt, true)
+
+	ch := make(chan int, 1)
+	ch <- 13
+	close(ch)
+	_, open := <-ch
+	assertEquals(open, true)
+	_, open = <-ch
+	assertEquals(open, false)
+
+	// The instrumented code spells out the types of the values.
+	// If a name in these types is shadowed at the assignment,
+	// the comma-ok form is left as-is.
+	urls := map[string]*url.URL{}
+	url := "key:" + "value"
+	u, hasURL := urls[url]
+	assertEquals(u, urls["other"])
+	assertEquals(hasURL, false)
+
+	keys := map[string]assignStmtKey{}
+	type assignStmtKey string
+	key, hasKey := keys["key"]
+	assertEquals(key, keys["other"])
+	assertEquals(hasKey, false)
+
+	// An assignment from a function call with two results is not a
+	// comma-ok form, so it is not instrumented.
+	b1, b2 = func() (bool, bool) { return true, false }()
 }
+
+type assignStmtKey int
@@ -1,5 +1,10 @@
 package instrumenter
 
+import (
+	"log"
+	"os"
+)
+
 // https://go.dev/ref/spec#Expression_statements
 
 // exprStmt covers the instrumentation of [ast.ExprStmt], which has the
@@ -21,3 +26,16 @@ func exprStmt(i int, ch map[bool]<-chan int) {
 	<-(ch[i > 12])
 	(<-(ch[i > 13]))
 }
+
+// exprStmtExit covers calls that terminate the process
+// without running the deferred functions.
+// The counts are persisted before.
+func exprStmtExit(i int) {
+	if i > 0 {
+		log.Fatal("positive")
+	}
+	if i < 0 {
+		log.Fatalf("negative %d", i)
+	}
+	os.Exit(i)
+}
@@ -13,4 +13,14 @@ func selectStmt(c chan int) {
 	select {
 	case c <- 1:
 	}
+
+	// In a select statement, the receive operation in a comma-ok form
+	// cannot be wrapped in a function call, therefore it is not
+	// instrumented.
+	m := map[bool]chan int{}
+	select {
+	case v, ok := <-m[len(m) > 0]:
+		_, _ = v, ok
+	default:
+	}
 }
@@ -1,5 +1,7 @@
 package instrumenter
 
+import "time"
+
 // https://go.dev/ref/spec#Switch_statements
 
 // TODO: Add systematic tests.
@@ -121,3 +123,84 @@ func switchStmtNested() {
 		}
 	}
 }
+
+type switchKind int
+
+const (
+	switchKindFile switchKind = iota
+	switchKindDir
+	switchKindArchive
+)
+
+// In a switch statement whose tag has an enum type, that is, an integer
+// type whose constants have distinct values that lie close together, the
+// values that are not handled by any of the case clauses are recorded as
+// well.
+func switchStmtEnum(kind switchKind) {
+	switch kind {
+	case switchKindFile:
+	default:
+	}
+
+	// If all values are handled by the case clauses, there are no values
+	// left to be recorded.
+	switch kind {
+	case switchKindFile, switchKindDir, switchKindArchive:
+	}
+
+	// A constant that is shadowed at the switch statement
+	// cannot be referred to, so its value is not recorded.
+	switchKindDir := "shadowed"
+	switch kind {
+	case switchKindFile:
+	}
+	_ = switchKindDir
+}
+
+// The constants of an imported enum type are referred to by the package
+// name, unless that name is shadowed at the switch statement.
+func switchStmtImportedEnum(day time.Weekday) {
+	switch day {
+	case time.Saturday, time.Sunday:
+	}
+
+	time := "shadowed"
+	switch day {
+	case 6, 0:
+	}
+	_ = time
+}
+
+type switchMode uint
+
+const (
+	switchModeRead switchMode = 1 << iota
+	switchModeWrite
+	switchModeExec
+	switchModeSticky
+	switchModeAll = switchModeRead | switchModeWrite | switchModeExec | switchModeSticky
+)
+
+type switchLevel int
+
+const (
+	switchLevelLow switchLevel = iota
+	switchLevelHigh
+	switchLevelDefault = switchLevelLow
+)
+
+// Types whose constants are bit masks, units or aliases are not enum types,
+// so the values that are not handled by the case clauses are not recorded.
+func switchStmtNoEnum(mode switchMode, level switchLevel, d time.Duration) {
+	switch mode {
+	case switchModeRead:
+	}
+
+	switch level {
+	case switchLevelHigh:
+	}
+
+	switch d {
+	case time.Second:
+	}
+}
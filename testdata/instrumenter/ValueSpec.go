@@ -22,6 +22,11 @@ func valueSpec() {
 		_ = 1 > 0
 		_ = 0 > 1
 	)
+
+	// Variable declarations can use the comma-ok forms as well.
+	m := map[string]int{}
+	var v, ok = m["key"]
+	_, _ = v, ok
 }
 
 // https://go.dev/ref/spec#Package_initialization
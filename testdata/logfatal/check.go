@@ -0,0 +1,9 @@
+package logfatal
+
+import "log"
+
+func check(ok bool) {
+	if !ok {
+		log.Fatalf("not ok")
+	}
+}
@@ -0,0 +1,11 @@
+package minimize
+
+func sign(i int) int {
+	if i < 0 {
+		return -1
+	}
+	if i > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,23 @@
+package minimize
+
+import "testing"
+
+func Test_negative(t *testing.T) {
+	sign(-1)
+}
+
+func Test_positive(t *testing.T) {
+	sign(1)
+}
+
+func Test_all(t *testing.T) {
+	for _, i := range []int{-1, 1} {
+		t.Run("", func(t *testing.T) {
+			sign(i)
+		})
+	}
+}
+
+func Test_zero(t *testing.T) {
+	sign(0)
+}
@@ -0,0 +1,12 @@
+package mutate
+
+func isSmall(i int) bool {
+	return i < 10
+}
+
+func clamp(i int) int {
+	if i > 100 {
+		return 100
+	}
+	return i
+}
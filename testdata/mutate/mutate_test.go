@@ -0,0 +1,18 @@
+package mutate
+
+import "testing"
+
+func Test_isSmall(t *testing.T) {
+	if !isSmall(5) {
+		t.Error("5 must be small")
+	}
+	if isSmall(50) {
+		t.Error("50 must not be small")
+	}
+}
+
+// Test_clamp covers the condition in clamp but doesn't check the result.
+func Test_clamp(t *testing.T) {
+	clamp(5)
+	clamp(500)
+}
@@ -0,0 +1,9 @@
+package osexit
+
+import "os"
+
+func check(ok bool) {
+	if !ok {
+		os.Exit(3)
+	}
+}
@@ -0,0 +1,9 @@
+package osexit
+
+import "testing"
+
+// Test_check terminates the test process in the code under test.
+func Test_check(t *testing.T) {
+	check(true)
+	check(false)
+}
@@ -0,0 +1,5 @@
+package ownsignal
+
+func isSmall(i int) bool {
+	return i < 10
+}
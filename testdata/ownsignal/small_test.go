@@ -0,0 +1,34 @@
+package ownsignal
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Test_isSmall handles a signal by itself,
+// which must be delivered exactly once.
+func Test_isSmall(t *testing.T) {
+	isSmall(5)
+	isSmall(50)
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	<-signals
+	time.Sleep(100 * time.Millisecond)
+	if len(signals) != 0 {
+		t.Fatal("the signal was delivered twice")
+	}
+}
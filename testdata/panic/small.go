@@ -0,0 +1,5 @@
+package panic
+
+func isSmall(i int) bool {
+	return i < 10
+}
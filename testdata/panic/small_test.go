@@ -0,0 +1,11 @@
+package panic
+
+import (
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	isSmall(5)
+	isSmall(50)
+	panic("setup failed")
+}
@@ -0,0 +1,5 @@
+package signal
+
+func isSmall(i int) bool {
+	return i < 10
+}
@@ -0,0 +1,25 @@
+package signal
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Test_isSmall terminates the test process by a signal,
+// as if the tests were interrupted.
+func Test_isSmall(t *testing.T) {
+	isSmall(5)
+	isSmall(50)
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Second)
+	t.Fatal("the signal should have terminated the process")
+}
@@ -0,0 +1,5 @@
+package subprocess
+
+func isSmall(i int) bool {
+	return i < 10
+}
@@ -0,0 +1,24 @@
+package subprocess
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// Test_isSmall runs the test binary a second time as a helper process.
+// The counts from both processes end up in the same stats file.
+func Test_isSmall(t *testing.T) {
+	isSmall(5)
+
+	if os.Getenv("GOBCO_TEST_HELPER") == "1" {
+		isSmall(50)
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^Test_isSmall$")
+	cmd.Env = append(os.Environ(), "GOBCO_TEST_HELPER=1")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s\n%s", err, out)
+	}
+}
@@ -0,0 +1,5 @@
+package testmainhelper
+
+func isSmall(i int) bool {
+	return i < 10
+}
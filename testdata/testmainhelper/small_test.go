@@ -0,0 +1,21 @@
+package testmainhelper
+
+import (
+	"os"
+	"testing"
+)
+
+// Some projects pass m.Run to a helper function,
+// which sets up the environment and exits.
+func TestMain(m *testing.M) {
+	runTests(m.Run)
+}
+
+func runTests(run func() int) {
+	os.Exit(run())
+}
+
+func Test_isSmall(t *testing.T) {
+	isSmall(5)
+	isSmall(50)
+}
@@ -0,0 +1,5 @@
+package testmainreturn
+
+func isSmall(i int) bool {
+	return i < 10
+}
@@ -0,0 +1,15 @@
+package testmainreturn
+
+import (
+	"testing"
+)
+
+// Since Go 1.15, TestMain may return instead of calling os.Exit.
+func TestMain(m *testing.M) {
+	m.Run()
+}
+
+func Test_isSmall(t *testing.T) {
+	isSmall(5)
+	isSmall(50)
+}
@@ -0,0 +1,5 @@
+package testmainverify
+
+func isSmall(i int) bool {
+	return i < 10
+}
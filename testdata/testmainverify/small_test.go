@@ -0,0 +1,24 @@
+package testmainverify
+
+import (
+	"os"
+	"testing"
+)
+
+// Some projects pass m to a helper function,
+// which runs the tests, verifies the results and exits.
+func TestMain(m *testing.M) {
+	verifyTestMain(m)
+}
+
+func verifyTestMain(m *testing.M) {
+	code := m.Run()
+	if code == 0 && !isSmall(5) {
+		code = 1
+	}
+	os.Exit(code)
+}
+
+func Test_isSmall(t *testing.T) {
+	isSmall(50)
+}
@@ -0,0 +1,5 @@
+package testpanic
+
+func isSmall(i int) bool {
+	return i < 10
+}
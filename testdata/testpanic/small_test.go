@@ -0,0 +1,16 @@
+package testpanic
+
+import (
+	"testing"
+)
+
+// Test_isSmall panics in a subtest,
+// which terminates the test process without returning from TestMain.
+func Test_isSmall(t *testing.T) {
+	isSmall(5)
+	isSmall(50)
+
+	t.Run("sub", func(t *testing.T) {
+		panic("test failed")
+	})
+}
@@ -0,0 +1,5 @@
+package who
+
+func isSmall(i int) bool {
+	return i < 10
+}
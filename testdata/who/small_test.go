@@ -0,0 +1,16 @@
+package who
+
+import "testing"
+
+func Test_small(t *testing.T) {
+	isSmall(5)
+}
+
+func Test_subtest(t *testing.T) {
+	t.Run("large", func(t *testing.T) {
+		isSmall(50)
+	})
+}
+
+func Test_other(t *testing.T) {
+}
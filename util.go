@@ -66,6 +66,21 @@ func copyFile(src string, dst string) (err error) {
 	return
 }
 
+// createTemp creates a new file next to filename,
+// to be renamed to filename later.
+// In contrast to os.CreateTemp, which only allows the owner
+// to read and write the file,
+// the permissions are the same as from os.Create.
+func createTemp(filename string) (*os.File, error) {
+	for {
+		name := filename + "." + randomHex(8) + ".tmp"
+		file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o666)
+		if !os.IsExist(err) {
+			return file, err
+		}
+	}
+}
+
 func randomHex(n int) string {
 	rnd := make([]byte, n)
 	_, err := io.ReadFull(rand.Reader, rnd[:])